@@ -5,12 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
-	"sort"
 	"strconv"
 
 	metrics "github.com/armon/go-metrics"
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/umbracle/ethgo"
+	"go.etcd.io/bbolt"
 
 	"github.com/0xPolygon/polygon-edge/consensus/polybft/common"
 	"github.com/0xPolygon/polygon-edge/consensus/polybft/contractsapi"
@@ -33,8 +33,10 @@ var (
 
 type CheckpointManager interface {
 	PostBlock(req *common.PostBlockRequest) error
-	BuildEventRoot(epoch uint64) (types.Hash, error)
+	BuildEventRoot(epoch uint64) (types.Hash, merkle.HashAlgorithm, error)
 	GenerateExitProof(exitID uint64) (types.Proof, error)
+	GenerateExitProofs(exitIDs []uint64) ([]types.Proof, error)
+	GenerateExitProofsByEpoch(epoch uint64) ([]types.Proof, error)
 	GenerateSlashExitProofs() ([]types.Proof, error)
 }
 
@@ -43,12 +45,18 @@ var _ CheckpointManager = (*dummyCheckpointManager)(nil)
 type dummyCheckpointManager struct{}
 
 func (d *dummyCheckpointManager) PostBlock(req *common.PostBlockRequest) error { return nil }
-func (d *dummyCheckpointManager) BuildEventRoot(epoch uint64) (types.Hash, error) {
-	return types.ZeroHash, nil
+func (d *dummyCheckpointManager) BuildEventRoot(epoch uint64) (types.Hash, merkle.HashAlgorithm, error) {
+	return types.ZeroHash, merkle.HashAlgorithmKeccak256, nil
 }
 func (d *dummyCheckpointManager) GenerateExitProof(exitID uint64) (types.Proof, error) {
 	return types.Proof{}, nil
 }
+func (d *dummyCheckpointManager) GenerateExitProofs(exitIDs []uint64) ([]types.Proof, error) {
+	return nil, nil
+}
+func (d *dummyCheckpointManager) GenerateExitProofsByEpoch(epoch uint64) ([]types.Proof, error) {
+	return nil, nil
+}
 func (d *dummyCheckpointManager) GenerateSlashExitProofs() ([]types.Proof, error) {
 	return nil, nil
 }
@@ -67,40 +75,108 @@ type checkpointManager struct {
 	rootChainRelayer txrelayer.TxRelayer
 	// checkpointManagerAddr is address of CheckpointManager smart contract
 	checkpointManagerAddr types.Address
+	// checkpointOracleAddr is address of the CheckpointOracle smart contract, used when
+	// CurrentClientConfig.CheckpointMode is CheckpointModeOracle
+	checkpointOracleAddr types.Address
 	// lastSentBlock represents the last block on which a checkpoint transaction was sent
 	lastSentBlock uint64
 	// logger instance
 	logger hclog.Logger
 	// state boltDb instance
 	state *State
-	// eventsGetter gets Ethereum events (missed or current) from blocks
-	eventsGetter *eventsGetter[contractsapi.EventAbi]
+	// attestationPool collects off-chain checkpoint signatures when operating in CheckpointModeOracle
+	attestationPool *checkpointAttestationPool
+	// attestationTransport gossips this node's checkpoint attestations to the rest of the validator set
+	attestationTransport CheckpointAttestationTransport
+	// dispatcher fans out each finalized block's logs to checkpointManager (and any other
+	// registered EventSubscriber) via DispatchWithRetry, called from PostBlock
+	dispatcher *eventDispatcher
+	// queue is the crash-safe, bbolt-backed checkpoint submission queue shared by producer and submitter
+	queue *checkpointQueueStore
+	// producer enqueues checkpoint-eligible blocks from PostBlock
+	producer *checkpointProducer
+	// submitter dequeues and submits pending checkpoints to the rootchain in the background
+	submitter *checkpointSubmitter
+	// exitTreeHashFunc is the hashing algorithm used to build the exit (event root) merkle
+	// tree for epochs not yet recorded in exitTreeHashAlgo (i.e. the algorithm about to be
+	// used for the next checkpoint); defaults to merkle.Keccak256HashFunc
+	exitTreeHashFunc merkle.HashFunc
+	// exitTreeHashAlgo records, per epoch, which algorithm that epoch's exit tree was
+	// actually built with, so a later config change can never retroactively break exit
+	// proofs for epochs checkpointed under the old algorithm
+	exitTreeHashAlgo *exitTreeHashAlgoStore
 }
 
-// newCheckpointManager creates a new instance of checkpointManager
+// newCheckpointManager creates a new instance of checkpointManager, registers it with the
+// given eventDispatcher so exit and slashed events reach it via GetLogFilters/ProcessLog,
+// and starts its background checkpointSubmitter. Callers must call Close on shutdown.
+// exitTreeHashFunc selects the exit tree's hashing algorithm for epochs checkpointed from
+// now on (e.g. merkle.Keccak256HashFunc or merkle.PoseidonHashFunc); if nil, it defaults to
+// merkle.Keccak256HashFunc. Epochs checkpointed earlier keep using whichever algorithm was
+// recorded for them in exitTreeHashAlgo, regardless of this setting.
+// attestationTransport is only needed in CheckpointModeOracle: it gossips this node's
+// attestations and, via Subscribe, delivers peers' attestations into ProcessCheckpointAttestation
+// so the elected leader actually accumulates a quorum instead of only ever seeing its own vote
 func newCheckpointManager(key ethgo.Key,
-	checkpointManagerSC types.Address, txRelayer txrelayer.TxRelayer,
+	checkpointManagerSC, checkpointOracleSC types.Address, txRelayer txrelayer.TxRelayer,
 	blockchain blockchainBackend, backend polybftBackend, logger hclog.Logger,
-	state *State) *checkpointManager {
-	eventsGetter := &eventsGetter[contractsapi.EventAbi]{
-		blockchain: blockchain,
-		isValidLogFn: func(l *types.Log) bool {
-			return l.Address == contracts.L2StateSenderContract ||
-				l.Address == contracts.ValidatorSetContract
-		},
-		parseEventFn: parseEvent,
+	state *State, dispatcher *eventDispatcher, db *bbolt.DB,
+	exitTreeHashFunc merkle.HashFunc,
+	attestationTransport CheckpointAttestationTransport) (*checkpointManager, error) {
+	queue, err := newCheckpointQueueStore(db)
+	if err != nil {
+		return nil, err
 	}
 
-	return &checkpointManager{
+	exitTreeHashAlgo, err := newExitTreeHashAlgoStore(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if exitTreeHashFunc == nil {
+		exitTreeHashFunc = merkle.Keccak256HashFunc
+	}
+
+	c := &checkpointManager{
 		key:                   key,
 		blockchain:            blockchain,
 		consensusBackend:      backend,
 		rootChainRelayer:      txRelayer,
 		checkpointManagerAddr: checkpointManagerSC,
+		checkpointOracleAddr:  checkpointOracleSC,
 		logger:                logger,
 		state:                 state,
-		eventsGetter:          eventsGetter,
+		attestationPool:       newCheckpointAttestationPool(),
+		attestationTransport:  attestationTransport,
+		dispatcher:            dispatcher,
+		queue:                 queue,
+		producer:              &checkpointProducer{queue: queue, logger: logger.Named("checkpoint-producer")},
+		exitTreeHashFunc:      exitTreeHashFunc,
+		exitTreeHashAlgo:      exitTreeHashAlgo,
+	}
+	c.submitter = newCheckpointSubmitter(c, queue, logger.Named("checkpoint-submitter"))
+
+	dispatcher.RegisterSubscriber(c)
+
+	if attestationTransport != nil {
+		if err := attestationTransport.Subscribe(func(attestation *CheckpointAttestation) {
+			if err := c.ProcessCheckpointAttestation(attestation); err != nil {
+				c.logger.Warn("failed to process checkpoint attestation",
+					"from", attestation.From, "block number", attestation.BlockNumber, "error", err)
+			}
+		}); err != nil {
+			return nil, fmt.Errorf("failed to subscribe to checkpoint attestation gossip: %w", err)
+		}
 	}
+
+	go c.submitter.run()
+
+	return c, nil
+}
+
+// Close stops the background checkpointSubmitter. It should be called once on node shutdown
+func (c *checkpointManager) Close() {
+	c.submitter.Close()
 }
 
 // getLatestCheckpointBlock queries CheckpointManager smart contract and retrieves latest checkpoint block number
@@ -127,84 +203,19 @@ func (c *checkpointManager) getLatestCheckpointBlock() (uint64, error) {
 	return latestCheckpointBlockNum, nil
 }
 
-// submitCheckpoint sends a transaction with checkpoint data to the rootchain
-func (c *checkpointManager) submitCheckpoint(latestHeader *types.Header, isEndOfEpoch bool) error {
-	lastCheckpointBlockNumber, err := c.getLatestCheckpointBlock()
-	if err != nil {
-		return err
-	}
-
-	c.logger.Debug("submitCheckpoint invoked...",
-		"latest checkpoint block", lastCheckpointBlockNumber,
-		"checkpoint block", latestHeader.Number)
-
-	var (
-		initialBlockNumber = lastCheckpointBlockNumber + 1
-		parentExtra        *Extra
-		parentHeader       *types.Header
-		currentExtra       *Extra
-		found              bool
-	)
-
-	if initialBlockNumber < latestHeader.Number {
-		parentHeader, found = c.blockchain.GetHeaderByNumber(initialBlockNumber)
-		if !found {
-			return fmt.Errorf("block %d was not found", initialBlockNumber)
-		}
-
-		parentExtra, err = GetIbftExtra(parentHeader.ExtraData)
-		if err != nil {
-			return err
-		}
-	}
-
-	// detect any pending (previously failed) checkpoints and send them
-	for blockNumber := initialBlockNumber + 1; blockNumber <= latestHeader.Number; blockNumber++ {
-		currentHeader, found := c.blockchain.GetHeaderByNumber(blockNumber)
-		if !found {
-			return fmt.Errorf("block %d was not found", blockNumber)
-		}
-
-		currentExtra, err = GetIbftExtra(currentHeader.ExtraData)
-		if err != nil {
-			return err
-		}
-
-		parentEpochNumber := parentExtra.Checkpoint.EpochNumber
-		currentEpochNumber := currentExtra.Checkpoint.EpochNumber
-		// send pending checkpoints only for epoch ending blocks
-		if blockNumber == 1 || parentEpochNumber == currentEpochNumber {
-			parentHeader = currentHeader
-			parentExtra = currentExtra
-
-			continue
-		}
-
-		if err = c.encodeAndSendCheckpoint(parentHeader, parentExtra, true); err != nil {
-			return err
-		}
-
-		parentHeader = currentHeader
-		parentExtra = currentExtra
-	}
-
-	// latestHeader extra could be set in the for loop above
-	// (in case there were pending checkpoint blocks)
-	if currentExtra == nil {
-		// we need to send checkpoint for the latest block
-		currentExtra, err = GetIbftExtra(latestHeader.ExtraData)
-		if err != nil {
-			return err
-		}
-	}
+// baseCheckpointTip is the priority fee that checkpoint submission retries are bumped from:
+// encodeAndSendCheckpoint sets it, raised by the bump percentage, on each retry a
+// checkpointSubmitter makes for the same block. The first attempt (no bump) leaves the
+// priority fee unset
+var baseCheckpointTip = big.NewInt(1_500_000_000) // 1.5 gwei
 
-	return c.encodeAndSendCheckpoint(latestHeader, currentExtra, isEndOfEpoch)
-}
-
-// encodeAndSendCheckpoint encodes checkpoint data for the given block and
-// sends a transaction to the CheckpointManager rootchain contract
-func (c *checkpointManager) encodeAndSendCheckpoint(header *types.Header, extra *Extra, isEndOfEpoch bool) error {
-	c.logger.Debug("send checkpoint txn...", "block number", header.Number)
+// encodeAndSendCheckpoint encodes checkpoint data for the given block and sends a
+// transaction to the CheckpointManager rootchain contract. gasPriceBumpPercent increases
+// the priority fee above baseCheckpointTip, so retries from checkpointSubmitter are more
+// likely to land instead of getting stuck behind an underpriced transaction
+func (c *checkpointManager) encodeAndSendCheckpoint(
+	header *types.Header, extra *Extra, isEndOfEpoch bool, gasPriceBumpPercent int) error {
+	c.logger.Debug("send checkpoint txn...", "block number", header.Number, "gasPriceBumpPercent", gasPriceBumpPercent)
 
 	checkpointManager := ethgo.Address(c.checkpointManagerAddr)
 
@@ -230,6 +241,12 @@ func (c *checkpointManager) encodeAndSendCheckpoint(header *types.Header, extra
 		Type:  ethgo.TransactionDynamicFee,
 	}
 
+	if gasPriceBumpPercent > 0 {
+		txn.MaxPriorityFeePerGas = new(big.Int).Div(
+			new(big.Int).Mul(baseCheckpointTip, big.NewInt(int64(100+gasPriceBumpPercent))),
+			big.NewInt(100))
+	}
+
 	receipt, err := c.rootChainRelayer.SendTransaction(txn, c.key)
 	if err != nil {
 		return err
@@ -285,108 +302,328 @@ func (c *checkpointManager) isCheckpointBlock(blockNumber, checkpointsOffset uin
 	return isEpochEndingBlock || blockNumber == c.lastSentBlock+checkpointsOffset
 }
 
-// PostBlock is called on every insert of finalized block (either from consensus or syncer)
-// It will read any exit event that happened in block and insert it to state boltDb
+// GetLogFilters returns the contracts and event signatures checkpointManager wants to
+// observe, so the eventDispatcher only forwards exit and slashed events to ProcessLog
+func (c *checkpointManager) GetLogFilters() map[types.Address][]types.Hash {
+	var (
+		exitEvent    ExitEvent
+		slashedEvent contractsapi.SlashedEvent
+	)
+
+	return map[types.Address][]types.Hash{
+		contracts.L2StateSenderContract: {types.Hash(exitEvent.Sig())},
+		contracts.ValidatorSetContract:  {types.Hash(slashedEvent.Sig())},
+	}
+}
+
+// ProcessLog handles a single exit or slashed event log, participating in the caller's
+// bbolt transaction so it commits atomically alongside every other subscriber of the block
+func (c *checkpointManager) ProcessLog(header *types.Header, log *ethgo.Log, dbTx *bbolt.Tx) error {
+	event, matches, err := parseEvent(header, log)
+	if err != nil {
+		return err
+	}
+
+	if !matches {
+		return nil
+	}
+
+	switch specificEvent := event.(type) {
+	case *ExitEvent:
+		if err := c.state.ExitEventStore.insertExitEvents([]*ExitEvent{specificEvent}, dbTx); err != nil {
+			return err
+		}
+
+		return c.state.ExitEventStore.updateLastSaved(header.Number, dbTx)
+	case *contractsapi.SlashedEvent:
+		return c.state.ExitEventStore.removeSlashExitEvents(dbTx, specificEvent.ExitID.Uint64())
+	}
+
+	return nil
+}
+
+// PostBlock is called on every insert of finalized block (either from consensus or syncer).
+// It first dispatches the block's logs to every registered EventSubscriber (see
+// GetLogFilters/ProcessLog) via eventDispatcher.DispatchWithRetry, which wraps the whole
+// block's writes in a single retried bbolt transaction, so exit and slashed events are never
+// dropped even if this node isn't the one submitting the checkpoint. It then drives checkpoint
+// submission. In CheckpointModeBLSAggregate, PostBlock no longer submits the rootchain
+// transaction itself - it only enqueues the block with checkpointProducer; checkpointSubmitter
+// dequeues and submits it in the background, with its own retry and gas price bumping,
+// surviving node restarts
 func (c *checkpointManager) PostBlock(req *common.PostBlockRequest) error {
-	block := req.FullBlock.Block.Number()
+	header := req.FullBlock.Block.Header
+
+	if err := c.dispatcher.DispatchWithRetry(header, blockLogs(header, req.FullBlock.Receipts)); err != nil {
+		return fmt.Errorf("failed to dispatch events for block %d: %w", header.Number, err)
+	}
+
+	if c.isCheckpointBlock(header.Number, req.CurrentClientConfig.CheckpointInterval, req.IsEpochEndingBlock) {
+		isEndOfEpoch := req.IsEpochEndingBlock
+		checkpointMode := req.CurrentClientConfig.CheckpointMode
+
+		switch {
+		// in oracle mode every validator attests to the checkpoint off-chain, so all of
+		// them (not just the block proposer) must run the submission path
+		case checkpointMode == CheckpointModeOracle:
+			go func(header *types.Header, epochNumber uint64) {
+				if err := c.submitOracleCheckpoint(header, isEndOfEpoch); err != nil {
+					c.logger.Warn("failed to submit oracle checkpoint",
+						"checkpoint block", header.Number, "epoch number", epochNumber, "error", err)
+				}
+			}(header, req.Epoch)
+
+			c.lastSentBlock = req.FullBlock.Block.Number()
+		case bytes.Equal(c.key.Address().Bytes(), header.Miner):
+			if err := c.producer.Enqueue(header.Number, isEndOfEpoch); err != nil {
+				c.logger.Warn("failed to enqueue checkpoint", "checkpoint block", header.Number, "error", err)
+			}
+
+			c.lastSentBlock = req.FullBlock.Block.Number()
+		}
+	}
+
+	return nil
+}
+
+// BuildEventRoot returns the exit event root hash for the exit tree of the given epoch,
+// along with the merkle.HashAlgorithm it was built with. Callers assembling a new checkpoint
+// block must stamp this algorithm onto that block's Extra.Checkpoint.HashAlgorithm alongside
+// the event root: that on-chain record, not this node's local exitTreeHashAlgo cache, is what
+// lets any other node - or a light client that never built the tree itself - later verify an
+// exit proof against the right algorithm
+func (c *checkpointManager) BuildEventRoot(epoch uint64) (types.Hash, merkle.HashAlgorithm, error) {
+	exitEvents, err := c.state.ExitEventStore.getExitEventsByEpoch(epoch)
+	if err != nil {
+		return types.ZeroHash, 0, err
+	}
+
+	if len(exitEvents) == 0 {
+		return types.ZeroHash, 0, nil
+	}
+
+	if err := c.exitTreeHashAlgo.recordIfAbsent(epoch, c.exitTreeHashFunc.Algorithm()); err != nil {
+		return types.ZeroHash, 0, fmt.Errorf("failed to record exit tree hash algorithm for epoch %d: %w", epoch, err)
+	}
 
-	lastBlock, err := c.state.ExitEventStore.getLastSaved()
+	algorithm, _, err := c.exitTreeHashAlgo.get(epoch)
 	if err != nil {
-		return fmt.Errorf("could not get last processed block for exit events. Error: %w", err)
+		return types.ZeroHash, 0, fmt.Errorf("failed to resolve exit tree hash algorithm for epoch %d: %w", epoch, err)
 	}
 
-	events, err := c.eventsGetter.getFromBlocks(lastBlock, req.FullBlock)
+	hashFunc, err := merkle.HashFuncByAlgorithm(algorithm)
 	if err != nil {
-		return err
+		return types.ZeroHash, 0, err
+	}
+
+	tree, err := createExitTree(exitEvents, hashFunc)
+	if err != nil {
+		return types.ZeroHash, 0, err
 	}
 
-	exitEvents := make([]*ExitEvent, 0, len(events))
-	slashedEvents := make([]*contractsapi.SlashedEvent, 0, len(events))
+	return tree.Hash(), tree.HashAlgorithm(), nil
+}
 
-	for _, e := range events {
-		switch specificEvent := e.(type) {
-		case *ExitEvent:
-			exitEvents = append(exitEvents, specificEvent)
-		case *contractsapi.SlashedEvent:
-			slashedEvents = append(slashedEvents, specificEvent)
+// hashFuncForEpoch resolves the merkle.HashFunc that epoch's exit tree was actually built
+// with. The authoritative source is the on-chain checkpoint block's Extra.Checkpoint.HashAlgorithm
+// (stamped there from BuildEventRoot's return value when the block was proposed), since that's
+// readable and verifiable by any node, not just the one that originally built the tree. If the
+// checkpoint block can't be resolved yet (e.g. a proof is requested for the current epoch
+// before it has been checkpointed on-chain), this falls back to this node's local
+// exitTreeHashAlgo record, and finally to Keccak256 - the only algorithm that existed before
+// HashAlgorithm was introduced
+func (c *checkpointManager) hashFuncForEpoch(epoch, checkpointBlock uint64) (merkle.HashFunc, error) {
+	if header, found := c.blockchain.GetHeaderByNumber(checkpointBlock); found {
+		if extra, err := GetIbftExtra(header.ExtraData); err == nil && extra.Checkpoint != nil {
+			return merkle.HashFuncByAlgorithm(extra.Checkpoint.HashAlgorithm)
 		}
 	}
 
-	sort.Slice(exitEvents, func(i, j int) bool {
-		// keep events in sequential order
-		return exitEvents[i].ID.Cmp(exitEvents[j].ID) < 0
-	})
+	algorithm, found, err := c.exitTreeHashAlgo.get(epoch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve exit tree hash algorithm for epoch %d: %w", epoch, err)
+	}
 
-	if err := c.state.ExitEventStore.insertExitEvents(exitEvents); err != nil {
-		return err
+	if !found {
+		return merkle.Keccak256HashFunc, nil
 	}
 
-	if err := c.state.ExitEventStore.updateLastSaved(block); err != nil {
-		return err
+	return merkle.HashFuncByAlgorithm(algorithm)
+}
+
+// GenerateExitProof generates proof of a single exit event. Unlike GenerateExitProofs, a
+// missing exit event is a hard error here - the batch form logs and skips missing IDs so
+// one bad ID in a big request doesn't sink the rest, but a single explicit request for an
+// ID that doesn't exist should fail with the actual reason why
+func (c *checkpointManager) GenerateExitProof(exitID uint64) (types.Proof, error) {
+	exitEvent, err := c.state.ExitEventStore.getExitEvent(exitID)
+	if err != nil {
+		return types.Proof{}, fmt.Errorf("failed to retrieve exit event (ID=%d): %w", exitID, err)
 	}
 
-	processedExitIDs := make([]uint64, len(slashedEvents))
-	for i, event := range slashedEvents {
-		processedExitIDs[i] = event.ExitID.Uint64()
+	proofs, err := c.generateExitProofsForEvents([]*ExitEvent{exitEvent})
+	if err != nil {
+		return types.Proof{}, err
 	}
 
-	if err := c.state.ExitEventStore.removeSlashExitEvents(processedExitIDs...); err != nil {
-		return err
+	if len(proofs) == 0 {
+		return types.Proof{}, fmt.Errorf("checkpoint block not found for exit ID %d", exitID)
 	}
 
-	if c.isCheckpointBlock(req.FullBlock.Block.Header.Number,
-		req.CurrentClientConfig.CheckpointInterval, req.IsEpochEndingBlock) &&
-		bytes.Equal(c.key.Address().Bytes(), req.FullBlock.Block.Header.Miner) {
-		go func(header *types.Header, epochNumber uint64) {
-			if err := c.submitCheckpoint(header, req.IsEpochEndingBlock); err != nil {
-				c.logger.Warn("failed to submit checkpoint",
-					"checkpoint block", header.Number,
-					"epoch number", epochNumber,
-					"error", err)
-			}
-		}(req.FullBlock.Block.Header, req.Epoch)
+	return proofs[0], nil
+}
+
+// GenerateExitProofs generates proofs for a batch of exit events. Exits are grouped by
+// (epoch, checkpoint block) so that the exit tree for a group is built, and the group's
+// checkpoint block resolved on the rootchain, only once - no matter how many of the
+// requested exitIDs fall in that group - instead of once per exit ID
+func (c *checkpointManager) GenerateExitProofs(exitIDs []uint64) ([]types.Proof, error) {
+	exitEvents := make([]*ExitEvent, 0, len(exitIDs))
+
+	for _, exitID := range exitIDs {
+		exitEvent, err := c.state.ExitEventStore.getExitEvent(exitID)
+		if err != nil {
+			c.logger.Info(fmt.Sprintf("failed to retrieve exit event (ID=%d): %v", exitID, err))
+
+			continue
+		}
 
-		c.lastSentBlock = req.FullBlock.Block.Number()
+		exitEvents = append(exitEvents, exitEvent)
 	}
 
-	return nil
+	return c.generateExitProofsForEvents(exitEvents)
 }
 
-// BuildEventRoot returns an exit event root hash for exit tree of given epoch
-func (c *checkpointManager) BuildEventRoot(epoch uint64) (types.Hash, error) {
+// GenerateExitProofsByEpoch generates proofs for every exit event recorded in the given
+// epoch, sharing the exit tree build and checkpoint block lookup across the whole epoch
+func (c *checkpointManager) GenerateExitProofsByEpoch(epoch uint64) ([]types.Proof, error) {
 	exitEvents, err := c.state.ExitEventStore.getExitEventsByEpoch(epoch)
 	if err != nil {
-		return types.ZeroHash, err
+		return nil, fmt.Errorf("failed to retrieve exit events for epoch %d: %w", epoch, err)
 	}
 
-	if len(exitEvents) == 0 {
-		return types.ZeroHash, nil
+	return c.generateExitProofsForEvents(exitEvents)
+}
+
+// exitProofGroupKey groups exit events that share both an epoch and a rootchain checkpoint
+// block, and therefore share a single exit tree and a single getCheckpointBlock lookup
+type exitProofGroupKey struct {
+	epoch           uint64
+	checkpointBlock uint64
+}
+
+// generateExitProofsForEvents groups exitEvents by (epoch, checkpoint block) and, per
+// group, resolves the checkpoint block and builds the exit tree exactly once before
+// generating every requested proof out of it
+func (c *checkpointManager) generateExitProofsForEvents(exitEvents []*ExitEvent) ([]types.Proof, error) {
+	checkpointBlockByBlockNumber := make(map[uint64]uint64, len(exitEvents))
+	groupOrder := make([]exitProofGroupKey, 0, len(exitEvents))
+	groups := make(map[exitProofGroupKey][]*ExitEvent, len(exitEvents))
+
+	for _, exitEvent := range exitEvents {
+		checkpointBlock, ok := checkpointBlockByBlockNumber[exitEvent.BlockNumber]
+		if !ok {
+			var err error
+
+			checkpointBlock, err = c.getCheckpointBlock(exitEvent.BlockNumber)
+			if err != nil {
+				c.logger.Info(fmt.Sprintf("failed to create a proof for exit event (ID=%d): %v", exitEvent.ID, err))
+
+				continue
+			}
+
+			checkpointBlockByBlockNumber[exitEvent.BlockNumber] = checkpointBlock
+		}
+
+		key := exitProofGroupKey{epoch: exitEvent.EpochNumber, checkpointBlock: checkpointBlock}
+		if _, exists := groups[key]; !exists {
+			groupOrder = append(groupOrder, key)
+		}
+
+		groups[key] = append(groups[key], exitEvent)
 	}
 
-	tree, err := createExitTree(exitEvents)
-	if err != nil {
-		return types.ZeroHash, err
+	proofs := make([]types.Proof, 0, len(exitEvents))
+
+	for _, key := range groupOrder {
+		groupProofs, err := c.generateExitProofsForGroup(key, groups[key])
+		if err != nil {
+			return nil, err
+		}
+
+		proofs = append(proofs, groupProofs...)
 	}
 
-	return tree.Hash(), nil
+	return proofs, nil
 }
 
-// GenerateExitProof generates proof of exit event
-func (c *checkpointManager) GenerateExitProof(exitID uint64) (types.Proof, error) {
-	c.logger.Debug("Generating proof for exit", "exitID", exitID)
+// generateExitProofsForGroup builds a single exit tree for all exit events checkpointed
+// together at (key.epoch, key.checkpointBlock), and generates a proof for each of requestedEvents
+func (c *checkpointManager) generateExitProofsForGroup(
+	key exitProofGroupKey, requestedEvents []*ExitEvent) ([]types.Proof, error) {
+	checkpointedEvents, err := c.state.ExitEventStore.getExitEventsForProof(key.epoch, key.checkpointBlock)
+	if err != nil {
+		return nil, err
+	}
 
-	exitEvent, err := c.state.ExitEventStore.getExitEvent(exitID)
+	hashFunc, err := c.hashFuncForEpoch(key.epoch, key.checkpointBlock)
 	if err != nil {
-		return types.Proof{}, err
+		return nil, err
+	}
+
+	tree, err := createExitTree(checkpointedEvents, hashFunc)
+	if err != nil {
+		return nil, err
 	}
 
+	var exitEventAPI contractsapi.L2StateSyncedEvent
+
+	checkpointBlock := new(big.Int).SetUint64(key.checkpointBlock)
+	proofs := make([]types.Proof, 0, len(requestedEvents))
+
+	for _, exitEvent := range requestedEvents {
+		encoded, err := exitEventAPI.Encode(exitEvent.L2StateSyncedEvent)
+		if err != nil {
+			return nil, err
+		}
+
+		leafIndex, err := tree.LeafIndex(encoded)
+		if err != nil {
+			return nil, err
+		}
+
+		proof, err := tree.GenerateProof(encoded)
+		if err != nil {
+			return nil, err
+		}
+
+		c.logger.Debug("Generated proof for exit",
+			"exitID", exitEvent.ID, "leafIndex", leafIndex, "proofLen", len(proof))
+
+		proofs = append(proofs, types.Proof{
+			Data: proof,
+			Metadata: map[string]interface{}{
+				"LeafIndex":       leafIndex,
+				"ExitEvent":       exitEvent,
+				"CheckpointBlock": checkpointBlock,
+				"HashAlgorithm":   tree.HashAlgorithm().String(),
+			},
+		})
+	}
+
+	return proofs, nil
+}
+
+// getCheckpointBlock resolves the rootchain checkpoint block number that covers the given
+// L2 block, via the CheckpointManager contract's getCheckpointBlock view function
+func (c *checkpointManager) getCheckpointBlock(blockNumber uint64) (uint64, error) {
 	getCheckpointBlockFn := &contractsapi.GetCheckpointBlockCheckpointManagerFn{
-		BlockNumber: new(big.Int).SetUint64(exitEvent.BlockNumber),
+		BlockNumber: new(big.Int).SetUint64(blockNumber),
 	}
 
 	input, err := getCheckpointBlockFn.EncodeAbi()
 	if err != nil {
-		return types.Proof{}, fmt.Errorf("failed to encode get checkpoint block input: %w", err)
+		return 0, fmt.Errorf("failed to encode get checkpoint block input: %w", err)
 	}
 
 	getCheckpointBlockResp, err := c.rootChainRelayer.Call(
@@ -394,81 +631,45 @@ func (c *checkpointManager) GenerateExitProof(exitID uint64) (types.Proof, error
 		ethgo.Address(c.checkpointManagerAddr),
 		input)
 	if err != nil {
-		return types.Proof{}, fmt.Errorf("failed to retrieve checkpoint block for exit ID %d: %w", exitID, err)
+		return 0, fmt.Errorf("failed to retrieve checkpoint block for block %d: %w", blockNumber, err)
 	}
 
 	getCheckpointBlockRespRaw, err := hex.DecodeHex(getCheckpointBlockResp)
 	if err != nil {
-		return types.Proof{}, fmt.Errorf("failed to decode hex response for exit ID %d: %w", exitID, err)
+		return 0, fmt.Errorf("failed to decode hex response for block %d: %w", blockNumber, err)
 	}
 
 	getCheckpointBlockGeneric, err := contractsapi.GetCheckpointBlockABIResponse.Decode(getCheckpointBlockRespRaw)
 	if err != nil {
-		return types.Proof{}, fmt.Errorf("failed to decode checkpoint block response for exit ID %d: %w", exitID, err)
+		return 0, fmt.Errorf("failed to decode checkpoint block response for block %d: %w", blockNumber, err)
 	}
 
 	checkpointBlockMap, ok := getCheckpointBlockGeneric.(map[string]interface{})
 	if !ok {
-		return types.Proof{}, fmt.Errorf("failed to convert for checkpoint block response exit ID %d", exitID)
+		return 0, fmt.Errorf("failed to convert checkpoint block response for block %d", blockNumber)
 	}
 
 	isFoundGeneric, ok := checkpointBlockMap["isFound"]
 	if !ok {
-		return types.Proof{}, fmt.Errorf("invalid response for exit ID %d", exitID)
+		return 0, fmt.Errorf("invalid checkpoint block response for block %d", blockNumber)
 	}
 
 	isCheckpointFound, ok := isFoundGeneric.(bool)
 	if !ok || !isCheckpointFound {
-		return types.Proof{}, fmt.Errorf("checkpoint block not found for exit ID %d", exitID)
+		return 0, fmt.Errorf("checkpoint block not found for block %d", blockNumber)
 	}
 
 	checkpointBlockGeneric, ok := checkpointBlockMap["checkpointBlock"]
 	if !ok {
-		return types.Proof{}, fmt.Errorf("checkpoint block not found for exit ID %d", exitID)
+		return 0, fmt.Errorf("checkpoint block not found for block %d", blockNumber)
 	}
 
 	checkpointBlock, ok := checkpointBlockGeneric.(*big.Int)
 	if !ok {
-		return types.Proof{}, fmt.Errorf("checkpoint block not found for exit ID %d", exitID)
-	}
-
-	var exitEventAPI contractsapi.L2StateSyncedEvent
-
-	e, err := exitEventAPI.Encode(exitEvent.L2StateSyncedEvent)
-	if err != nil {
-		return types.Proof{}, err
-	}
-
-	exitEvents, err := c.state.ExitEventStore.getExitEventsForProof(exitEvent.EpochNumber, checkpointBlock.Uint64())
-	if err != nil {
-		return types.Proof{}, err
-	}
-
-	tree, err := createExitTree(exitEvents)
-	if err != nil {
-		return types.Proof{}, err
-	}
-
-	leafIndex, err := tree.LeafIndex(e)
-	if err != nil {
-		return types.Proof{}, err
+		return 0, fmt.Errorf("checkpoint block not found for block %d", blockNumber)
 	}
 
-	proof, err := tree.GenerateProof(e)
-	if err != nil {
-		return types.Proof{}, err
-	}
-
-	c.logger.Debug("Generated proof for exit", "exitID", exitID, "leafIndex", leafIndex, "proofLen", len(proof))
-
-	return types.Proof{
-		Data: proof,
-		Metadata: map[string]interface{}{
-			"LeafIndex":       leafIndex,
-			"ExitEvent":       exitEvent,
-			"CheckpointBlock": checkpointBlock,
-		},
-	}, nil
+	return checkpointBlock.Uint64(), nil
 }
 
 // GenerateSlashExitProofs generates proofs per each slash exit event found in the exit events store
@@ -478,24 +679,11 @@ func (c *checkpointManager) GenerateSlashExitProofs() ([]types.Proof, error) {
 		return nil, fmt.Errorf("failed to retrieve pending slash exit ids: %w", err)
 	}
 
-	proofs := make([]types.Proof, 0, len(slashExitIDs))
-
-	for _, slashExitID := range slashExitIDs {
-		proof, err := c.GenerateExitProof(slashExitID)
-		if err != nil {
-			c.logger.Info(fmt.Sprintf("failed to create a proof for slash exit event (ID=%d): %v", slashExitID, err))
-
-			continue
-		}
-
-		proofs = append(proofs, proof)
-	}
-
-	return proofs, nil
+	return c.GenerateExitProofs(slashExitIDs)
 }
 
 // createExitTree creates an exit event merkle tree from provided exit events
-func createExitTree(exitEvents []*ExitEvent) (*merkle.MerkleTree, error) {
+func createExitTree(exitEvents []*ExitEvent, hashFunc merkle.HashFunc) (*merkle.MerkleTree, error) {
 	numOfEvents := len(exitEvents)
 	data := make([][]byte, numOfEvents)
 
@@ -509,7 +697,43 @@ func createExitTree(exitEvents []*ExitEvent) (*merkle.MerkleTree, error) {
 		data[i] = b
 	}
 
-	return merkle.NewMerkleTree(data)
+	return merkle.NewMerkleTreeWithHashFunc(data, hashFunc)
+}
+
+// blockLogs flattens every log emitted by the block's transactions, in their on-chain order,
+// into the ethgo.Log shape eventDispatcher.DispatchWithRetry and EventSubscriber.ProcessLog expect
+func blockLogs(header *types.Header, receipts []*types.Receipt) []*ethgo.Log {
+	var logs []*ethgo.Log
+
+	var logIndex uint64
+
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			logs = append(logs, convertLog(header, receipt, logIndex, log))
+			logIndex++
+		}
+	}
+
+	return logs
+}
+
+// convertLog maps a types.Log emitted by a transaction onto ethgo's log type, stamping in the
+// block and transaction metadata the log itself doesn't carry
+func convertLog(header *types.Header, receipt *types.Receipt, logIndex uint64, log *types.Log) *ethgo.Log {
+	topics := make([]ethgo.Hash, len(log.Topics))
+	for i, topic := range log.Topics {
+		topics[i] = ethgo.Hash(topic)
+	}
+
+	return &ethgo.Log{
+		Address:         ethgo.Address(log.Address),
+		Topics:          topics,
+		Data:            log.Data,
+		BlockHash:       ethgo.Hash(header.Hash),
+		BlockNumber:     header.Number,
+		TransactionHash: ethgo.Hash(receipt.TxHash),
+		LogIndex:        logIndex,
+	}
 }
 
 // parseEvent parses event (either exit or slashed event) from the provided log
@@ -0,0 +1,85 @@
+package polybft
+
+import (
+	"path/filepath"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+
+	"github.com/0xPolygon/polygon-edge/consensus/polybft/validator"
+	"github.com/0xPolygon/polygon-edge/txrelayer"
+	"github.com/0xPolygon/polygon-edge/types"
+)
+
+// newTestCheckpointManagerAgainstSimulatedRelayer builds a checkpointManager whose
+// rootChainRelayer is a real txrelayer.SimulatedRelayer - a CheckpointManager contract
+// deployed on an in-memory chain - so GenerateExitProof/GenerateSlashExitProofs and
+// encodeAndSendCheckpoint exercise the genuine ABI-encode/Call/SendTransaction pipeline
+// instead of a hand-rolled fake of it
+func newTestCheckpointManagerAgainstSimulatedRelayer(t *testing.T) (*checkpointManager, *txrelayer.SimulatedRelayer) {
+	t.Helper()
+
+	relayer, err := txrelayer.NewSimulatedRelayer(validator.AccountSet{})
+	require.NoError(t, err)
+
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "checkpoint_manager_test.db"), 0666, nil)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	exitEventStore, err := newExitEventStore(db)
+	require.NoError(t, err)
+
+	return &checkpointManager{
+		key:                   newRawEcdsaKey(t),
+		rootChainRelayer:      relayer,
+		checkpointManagerAddr: relayer.CheckpointManagerAddr,
+		logger:                hclog.NewNullLogger(),
+		state:                 &State{ExitEventStore: exitEventStore},
+	}, relayer
+}
+
+func TestCheckpointManager_EncodeAndSendCheckpointRejectsInvalidAggregatedSignature(t *testing.T) {
+	c, _ := newTestCheckpointManagerAgainstSimulatedRelayer(t)
+
+	header := &types.Header{Number: 1, Hash: types.Hash{1}}
+	extra := &Extra{
+		Checkpoint: &CheckpointData{EpochNumber: 1},
+		Committed:  &Signature{AggregatedSignature: []byte("not a valid bls signature"), Bitmap: []byte{}},
+	}
+
+	// a malformed aggregated signature must be rejected while encoding the checkpoint,
+	// before any transaction is ever sent to the rootchain
+	err := c.encodeAndSendCheckpoint(header, extra, false, 0)
+	assert.Error(t, err)
+}
+
+func TestCheckpointManager_GenerateExitProofFailsWhenCheckpointBlockNotFound(t *testing.T) {
+	c, _ := newTestCheckpointManagerAgainstSimulatedRelayer(t)
+
+	insertTestExitEvents(t, c.state.ExitEventStore, &ExitEvent{ID: 1, EpochNumber: 1, BlockNumber: 5})
+
+	// the real, deployed CheckpointManager contract genuinely has no checkpoint covering
+	// block 5 yet, so getCheckpointBlock's on-chain lookup - not a stub - is what rejects this
+	_, err := c.GenerateExitProof(1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checkpoint block not found")
+}
+
+func TestCheckpointManager_GenerateSlashExitProofsAgainstUncheckpointedEvent(t *testing.T) {
+	c, _ := newTestCheckpointManagerAgainstSimulatedRelayer(t)
+
+	insertTestExitEvents(t, c.state.ExitEventStore, &ExitEvent{ID: 7, EpochNumber: 1, BlockNumber: 5})
+	require.NoError(t, c.state.ExitEventStore.db.Update(func(tx *bbolt.Tx) error {
+		return c.state.ExitEventStore.removeSlashExitEvents(tx, 7)
+	}))
+
+	// GenerateExitProofs (unlike GenerateExitProof) treats an unresolved checkpoint block as
+	// skip-and-continue rather than a hard error, so this comes back empty rather than failing
+	proofs, err := c.GenerateSlashExitProofs()
+	require.NoError(t, err)
+	assert.Empty(t, proofs)
+}
@@ -0,0 +1,384 @@
+package polybft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/umbracle/ethgo"
+
+	"github.com/0xPolygon/polygon-edge/consensus/polybft/contractsapi"
+	"github.com/0xPolygon/polygon-edge/consensus/polybft/validator"
+	"github.com/0xPolygon/polygon-edge/crypto"
+	"github.com/0xPolygon/polygon-edge/types"
+)
+
+// CheckpointMode determines how checkpointManager submits checkpoints to the rootchain
+type CheckpointMode string
+
+const (
+	// CheckpointModeBLSAggregate is the legacy path where every validator races to submit
+	// a checkpoint transaction carrying the aggregated BLS signature of the consensus committee
+	CheckpointModeBLSAggregate CheckpointMode = "bls-aggregate"
+
+	// CheckpointModeOracle is the off-chain oracle path: validators sign the checkpoint
+	// payload independently of consensus and a single elected leader submits the collected
+	// signatures to a CheckpointOracle contract
+	CheckpointModeOracle CheckpointMode = "oracle"
+)
+
+var (
+	errNoOracleValidators     = errors.New("no validators available for oracle leader election")
+	errOracleSignerNotInSet   = errors.New("attestation signer is not part of the active validator set")
+	errOracleInvalidSignature = errors.New("attestation signature does not match its payload")
+	errOracleNoQuorum         = errors.New("no single checkpoint payload has gathered a quorum of attestations yet")
+)
+
+// CheckpointAttestationPayload is the canonical, signable representation of a checkpoint block.
+// Validators operating in CheckpointModeOracle sign this payload off-chain instead of
+// contributing to the consensus BLS aggregate
+type CheckpointAttestationPayload struct {
+	BlockHash               types.Hash
+	BlockRound              uint64
+	EpochNumber             uint64
+	EventRoot               types.Hash
+	CurrentValidatorSetHash types.Hash
+	NewValidatorSet         validator.AccountSet
+}
+
+// Hash returns the hash validators sign over when attesting to a checkpoint payload
+func (p *CheckpointAttestationPayload) Hash() (types.Hash, error) {
+	newValidatorSetHash, err := p.NewValidatorSet.Hash()
+	if err != nil {
+		return types.Hash{}, fmt.Errorf("failed to hash next validator set: %w", err)
+	}
+
+	var roundBuf, epochBuf [8]byte
+
+	binary.BigEndian.PutUint64(roundBuf[:], p.BlockRound)
+	binary.BigEndian.PutUint64(epochBuf[:], p.EpochNumber)
+
+	buf := make([]byte, 0, types.HashLength*4+len(roundBuf)+len(epochBuf))
+	buf = append(buf, p.BlockHash.Bytes()...)
+	buf = append(buf, roundBuf[:]...)
+	buf = append(buf, epochBuf[:]...)
+	buf = append(buf, p.EventRoot.Bytes()...)
+	buf = append(buf, p.CurrentValidatorSetHash.Bytes()...)
+	buf = append(buf, newValidatorSetHash.Bytes()...)
+
+	return types.BytesToHash(crypto.Keccak256(buf)), nil
+}
+
+// CheckpointAttestation is a single validator's off-chain signature over a checkpoint payload.
+// It is gossiped over the existing consensus transport so that the elected leader can
+// collect an M-of-N quorum before submitting to the CheckpointOracle contract
+type CheckpointAttestation struct {
+	Epoch       uint64
+	BlockNumber uint64
+	Payload     *CheckpointAttestationPayload
+	Signature   []byte
+	From        types.Address
+}
+
+// CheckpointAttestationTransport gossips CheckpointAttestation messages to the rest
+// of the validator set over the node's existing consensus transport
+type CheckpointAttestationTransport interface {
+	// Gossip broadcasts a CheckpointAttestation to the rest of the validator set
+	Gossip(attestation *CheckpointAttestation) error
+	// Subscribe registers handler to be invoked for every CheckpointAttestation gossiped by
+	// a peer, including this node's own messages. It is called once, at construction time
+	Subscribe(handler func(*CheckpointAttestation)) error
+}
+
+// attestationKey identifies the (epoch, blockNumber) bucket a CheckpointAttestation belongs to
+type attestationKey struct {
+	epoch       uint64
+	blockNumber uint64
+}
+
+// checkpointAttestationPool accumulates off-chain checkpoint attestations, keyed by
+// (epoch, blockNumber), until the elected leader has gathered a quorum
+type checkpointAttestationPool struct {
+	mu           sync.RWMutex
+	attestations map[attestationKey]map[types.Address]*CheckpointAttestation
+}
+
+// newCheckpointAttestationPool creates an empty checkpointAttestationPool
+func newCheckpointAttestationPool() *checkpointAttestationPool {
+	return &checkpointAttestationPool{
+		attestations: make(map[attestationKey]map[types.Address]*CheckpointAttestation),
+	}
+}
+
+// add inserts an attestation into the pool, keyed by its signer
+func (p *checkpointAttestationPool) add(attestation *CheckpointAttestation) {
+	key := attestationKey{epoch: attestation.Epoch, blockNumber: attestation.BlockNumber}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	bucket, ok := p.attestations[key]
+	if !ok {
+		bucket = make(map[types.Address]*CheckpointAttestation)
+		p.attestations[key] = bucket
+	}
+
+	bucket[attestation.From] = attestation
+}
+
+// get returns all attestations collected so far for the given (epoch, blockNumber)
+func (p *checkpointAttestationPool) get(epoch, blockNumber uint64) map[types.Address]*CheckpointAttestation {
+	key := attestationKey{epoch: epoch, blockNumber: blockNumber}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	bucket := p.attestations[key]
+	result := make(map[types.Address]*CheckpointAttestation, len(bucket))
+
+	for addr, attestation := range bucket {
+		result[addr] = attestation
+	}
+
+	return result
+}
+
+// prune discards attestations up to and including the given epoch, since once a
+// checkpoint for an epoch is submitted (or superseded) its attestations are no longer needed
+func (p *checkpointAttestationPool) prune(epoch uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key := range p.attestations {
+		if key.epoch <= epoch {
+			delete(p.attestations, key)
+		}
+	}
+}
+
+// electOracleLeader deterministically picks the validator responsible for submitting
+// the oracle checkpoint transaction for the given block, reusing the active validator set
+func electOracleLeader(validators validator.AccountSet, blockNumber uint64) (types.Address, error) {
+	if len(validators) == 0 {
+		return types.ZeroAddress, errNoOracleValidators
+	}
+
+	return validators[blockNumber%uint64(len(validators))].Address, nil
+}
+
+// oracleQuorumSize returns the minimum number of signatures (M) required out of
+// the N active validators for the CheckpointOracle contract to accept a submission
+func oracleQuorumSize(validatorCount int) int {
+	return validatorCount*2/3 + 1
+}
+
+// submitOracleCheckpoint signs the checkpoint payload for the given block, gossips the
+// signature to the rest of the validator set and, if this node is the elected leader and a
+// quorum is already available, submits it to the CheckpointOracle contract
+func (c *checkpointManager) submitOracleCheckpoint(header *types.Header, isEndOfEpoch bool) error {
+	extra, err := GetIbftExtra(header.ExtraData)
+	if err != nil {
+		return err
+	}
+
+	nextEpochValidators := validator.AccountSet{}
+
+	if isEndOfEpoch {
+		nextEpochValidators, err = c.consensusBackend.GetValidators(header.Number, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	payload := &CheckpointAttestationPayload{
+		BlockHash:               header.Hash,
+		BlockRound:              extra.Checkpoint.BlockRound,
+		EpochNumber:             extra.Checkpoint.EpochNumber,
+		EventRoot:               extra.Checkpoint.EventRoot,
+		CurrentValidatorSetHash: extra.Checkpoint.CurrentValidatorsHash,
+		NewValidatorSet:         nextEpochValidators,
+	}
+
+	hash, err := payload.Hash()
+	if err != nil {
+		return fmt.Errorf("failed to hash checkpoint attestation payload for block %d: %w", header.Number, err)
+	}
+
+	signature, err := c.key.Sign(hash.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to sign checkpoint attestation payload for block %d: %w", header.Number, err)
+	}
+
+	attestation := &CheckpointAttestation{
+		Epoch:       extra.Checkpoint.EpochNumber,
+		BlockNumber: header.Number,
+		Payload:     payload,
+		Signature:   signature,
+		From:        types.Address(c.key.Address()),
+	}
+
+	c.attestationPool.add(attestation)
+
+	if c.attestationTransport != nil {
+		if err := c.attestationTransport.Gossip(attestation); err != nil {
+			c.logger.Warn("failed to gossip checkpoint attestation",
+				"block number", header.Number, "error", err)
+		}
+	}
+
+	return c.tryFinalizeOracleCheckpoint(extra.Checkpoint.EpochNumber, header.Number)
+}
+
+// ProcessCheckpointAttestation validates and records a checkpoint attestation received
+// from a peer, and attempts to finalize the oracle checkpoint if this node is the leader
+func (c *checkpointManager) ProcessCheckpointAttestation(attestation *CheckpointAttestation) error {
+	validators, err := c.consensusBackend.GetValidators(attestation.BlockNumber, nil)
+	if err != nil {
+		return err
+	}
+
+	if !validators.ContainsAddress(attestation.From) {
+		return errOracleSignerNotInSet
+	}
+
+	hash, err := attestation.Payload.Hash()
+	if err != nil {
+		return err
+	}
+
+	recoveredSigner, err := ethgo.Ecrecover(hash.Bytes(), attestation.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errOracleInvalidSignature, err)
+	}
+
+	if types.Address(recoveredSigner) != attestation.From {
+		return errOracleInvalidSignature
+	}
+
+	c.attestationPool.add(attestation)
+
+	return c.tryFinalizeOracleCheckpoint(attestation.Epoch, attestation.BlockNumber)
+}
+
+// tryFinalizeOracleCheckpoint submits the collected attestations to the CheckpointOracle
+// contract once a single payload has gathered a quorum of matching attestations, but only
+// if this node is the elected leader for the block
+func (c *checkpointManager) tryFinalizeOracleCheckpoint(epoch, blockNumber uint64) error {
+	validators, err := c.consensusBackend.GetValidators(blockNumber, nil)
+	if err != nil {
+		return err
+	}
+
+	leader, err := electOracleLeader(validators, blockNumber)
+	if err != nil {
+		return err
+	}
+
+	if leader != types.Address(c.key.Address()) {
+		return nil
+	}
+
+	attestations := c.attestationPool.get(epoch, blockNumber)
+
+	payload, matching, err := quorumPayload(attestations, oracleQuorumSize(len(validators)))
+	if err != nil {
+		if errors.Is(err, errOracleNoQuorum) {
+			return nil
+		}
+
+		return err
+	}
+
+	if err := c.submitWithOracleSignatures(blockNumber, payload, matching); err != nil {
+		return err
+	}
+
+	c.attestationPool.prune(epoch)
+
+	return nil
+}
+
+// quorumPayload groups attestations by the hash of the payload they actually signed and
+// returns the first payload (and its signers) to reach quorumSize matching attestations.
+// Grouping by payload hash first is what keeps a stale or byzantine minority attestation -
+// one that disagrees with the rest on the event root, round or next validator set - from
+// ever being aggregated alongside signatures collected over a different payload
+func quorumPayload(
+	attestations map[types.Address]*CheckpointAttestation, quorumSize int,
+) (*CheckpointAttestationPayload, []*CheckpointAttestation, error) {
+	groups := make(map[types.Hash][]*CheckpointAttestation, len(attestations))
+
+	for _, attestation := range attestations {
+		hash, err := attestation.Payload.Hash()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to hash checkpoint attestation payload: %w", err)
+		}
+
+		groups[hash] = append(groups[hash], attestation)
+	}
+
+	for _, group := range groups {
+		if len(group) >= quorumSize {
+			return group[0].Payload, group, nil
+		}
+	}
+
+	return nil, nil, errOracleNoQuorum
+}
+
+// submitWithOracleSignatures sends a submitWithOracleSignatures transaction to the
+// CheckpointOracle contract, carrying payload and the signatures collected over that exact
+// payload. Callers must ensure every attestation in attestations signed payload - see quorumPayload
+func (c *checkpointManager) submitWithOracleSignatures(
+	blockNumber uint64, payload *CheckpointAttestationPayload, attestations []*CheckpointAttestation) error {
+	signatures := make([][]byte, 0, len(attestations))
+	signers := make([]ethgo.Address, 0, len(attestations))
+
+	for _, attestation := range attestations {
+		signatures = append(signatures, attestation.Signature)
+		signers = append(signers, ethgo.Address(attestation.From))
+	}
+
+	submit := &contractsapi.SubmitWithOracleSignaturesCheckpointOracleFn{
+		CheckpointMetadata: &contractsapi.CheckpointMetadata{
+			BlockHash:               payload.BlockHash,
+			BlockRound:              new(big.Int).SetUint64(payload.BlockRound),
+			CurrentValidatorSetHash: payload.CurrentValidatorSetHash,
+		},
+		Checkpoint: &contractsapi.Checkpoint{
+			Epoch:       new(big.Int).SetUint64(payload.EpochNumber),
+			BlockNumber: new(big.Int).SetUint64(blockNumber),
+			EventRoot:   payload.EventRoot,
+		},
+		Signers:         signers,
+		Signatures:      signatures,
+		NewValidatorSet: payload.NewValidatorSet.ToAPIBinding(),
+	}
+
+	input, err := submit.EncodeAbi()
+	if err != nil {
+		return fmt.Errorf("failed to encode oracle checkpoint data for block %d: %w", blockNumber, err)
+	}
+
+	checkpointOracle := ethgo.Address(c.checkpointOracleAddr)
+	txn := &ethgo.Transaction{
+		To:    &checkpointOracle,
+		Input: input,
+		Type:  ethgo.TransactionDynamicFee,
+	}
+
+	receipt, err := c.rootChainRelayer.SendTransaction(txn, c.key)
+	if err != nil {
+		return err
+	}
+
+	if receipt.Status == uint64(types.ReceiptFailed) {
+		return fmt.Errorf("oracle checkpoint submission transaction failed for block %d", blockNumber)
+	}
+
+	c.logger.Debug("submitted oracle checkpoint", "block number", blockNumber, "signers", len(signers))
+
+	return nil
+}
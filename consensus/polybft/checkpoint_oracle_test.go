@@ -0,0 +1,190 @@
+package polybft
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo"
+
+	"github.com/0xPolygon/polygon-edge/consensus/polybft/validator"
+	"github.com/0xPolygon/polygon-edge/types"
+)
+
+// rawEcdsaKey is a minimal ethgo.Key backed directly by an ecdsa.PrivateKey, used only to
+// sign/recover checkpoint attestations in tests
+type rawEcdsaKey struct {
+	priv *ecdsa.PrivateKey
+}
+
+func newRawEcdsaKey(t *testing.T) *rawEcdsaKey {
+	t.Helper()
+
+	priv, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+
+	return &rawEcdsaKey{priv: priv}
+}
+
+func (k *rawEcdsaKey) Address() ethgo.Address {
+	return ethgo.Address(ethcrypto.PubkeyToAddress(k.priv.PublicKey))
+}
+
+func (k *rawEcdsaKey) Sign(hash []byte) ([]byte, error) {
+	return ethcrypto.Sign(hash, k.priv)
+}
+
+func testAttestationPayload() *CheckpointAttestationPayload {
+	return &CheckpointAttestationPayload{
+		BlockHash:       types.Hash{1},
+		BlockRound:      1,
+		EpochNumber:     1,
+		EventRoot:       types.Hash{2},
+		NewValidatorSet: validator.AccountSet{},
+	}
+}
+
+func signedAttestation(t *testing.T, key *rawEcdsaKey, payload *CheckpointAttestationPayload) *CheckpointAttestation {
+	t.Helper()
+
+	hash, err := payload.Hash()
+	require.NoError(t, err)
+
+	signature, err := key.Sign(hash.Bytes())
+	require.NoError(t, err)
+
+	return &CheckpointAttestation{
+		Epoch:       payload.EpochNumber,
+		BlockNumber: 1,
+		Payload:     payload,
+		Signature:   signature,
+		From:        types.Address(key.Address()),
+	}
+}
+
+func TestElectOracleLeader_NoValidators(t *testing.T) {
+	_, err := electOracleLeader(validator.AccountSet{}, 1)
+	assert.ErrorIs(t, err, errNoOracleValidators)
+}
+
+func TestElectOracleLeader_Deterministic(t *testing.T) {
+	validators := validator.AccountSet{
+		&validator.ValidatorMetadata{Address: types.Address{1}},
+		&validator.ValidatorMetadata{Address: types.Address{2}},
+		&validator.ValidatorMetadata{Address: types.Address{3}},
+	}
+
+	leaderA, err := electOracleLeader(validators, 7)
+	require.NoError(t, err)
+
+	leaderB, err := electOracleLeader(validators, 7)
+	require.NoError(t, err)
+
+	assert.Equal(t, leaderA, leaderB)
+	assert.Equal(t, validators[7%uint64(len(validators))].Address, leaderA)
+}
+
+func TestQuorumPayload_NoQuorumYet(t *testing.T) {
+	keyA := newRawEcdsaKey(t)
+	payload := testAttestationPayload()
+	attestationA := signedAttestation(t, keyA, payload)
+
+	attestations := map[types.Address]*CheckpointAttestation{attestationA.From: attestationA}
+
+	_, _, err := quorumPayload(attestations, 2)
+	assert.ErrorIs(t, err, errOracleNoQuorum)
+}
+
+func TestQuorumPayload_IgnoresMismatchedPayloads(t *testing.T) {
+	keyA, keyB, keyC := newRawEcdsaKey(t), newRawEcdsaKey(t), newRawEcdsaKey(t)
+
+	majorityPayload := testAttestationPayload()
+	staleMinorityPayload := testAttestationPayload()
+	staleMinorityPayload.BlockRound = 99
+
+	attestationA := signedAttestation(t, keyA, majorityPayload)
+	attestationB := signedAttestation(t, keyB, majorityPayload)
+	attestationC := signedAttestation(t, keyC, staleMinorityPayload)
+
+	attestations := map[types.Address]*CheckpointAttestation{
+		attestationA.From: attestationA,
+		attestationB.From: attestationB,
+		attestationC.From: attestationC,
+	}
+
+	payload, matching, err := quorumPayload(attestations, 2)
+	require.NoError(t, err)
+	assert.Equal(t, majorityPayload, payload)
+	assert.Len(t, matching, 2)
+
+	for _, attestation := range matching {
+		assert.NotEqual(t, attestationC.From, attestation.From)
+	}
+}
+
+func TestQuorumPayload_StillNoQuorumWhenSignersSplitAcrossPayloads(t *testing.T) {
+	keyA, keyB := newRawEcdsaKey(t), newRawEcdsaKey(t)
+
+	payloadA := testAttestationPayload()
+	payloadB := testAttestationPayload()
+	payloadB.BlockRound = 2
+
+	attestationA := signedAttestation(t, keyA, payloadA)
+	attestationB := signedAttestation(t, keyB, payloadB)
+
+	attestations := map[types.Address]*CheckpointAttestation{
+		attestationA.From: attestationA,
+		attestationB.From: attestationB,
+	}
+
+	_, _, err := quorumPayload(attestations, 2)
+	assert.ErrorIs(t, err, errOracleNoQuorum)
+}
+
+// fakeOracleBackend is a minimal polybftBackend stand-in covering the GetValidators call
+// ProcessCheckpointAttestation's validation path makes
+type fakeOracleBackend struct {
+	validators validator.AccountSet
+}
+
+func (f *fakeOracleBackend) GetValidators(uint64, []*types.Header) (validator.AccountSet, error) {
+	return f.validators, nil
+}
+
+func TestProcessCheckpointAttestation_RejectsSignerNotInValidatorSet(t *testing.T) {
+	key := newRawEcdsaKey(t)
+	attestation := signedAttestation(t, key, testAttestationPayload())
+
+	c := &checkpointManager{
+		consensusBackend: &fakeOracleBackend{validators: validator.AccountSet{
+			&validator.ValidatorMetadata{Address: types.Address{9}},
+		}},
+		attestationPool: newCheckpointAttestationPool(),
+	}
+
+	err := c.ProcessCheckpointAttestation(attestation)
+	assert.ErrorIs(t, err, errOracleSignerNotInSet)
+}
+
+func TestProcessCheckpointAttestation_RejectsInvalidSignature(t *testing.T) {
+	key := newRawEcdsaKey(t)
+	attestation := signedAttestation(t, key, testAttestationPayload())
+
+	// tamper with the signature so it no longer recovers to attestation.From
+	tampered := make([]byte, len(attestation.Signature))
+	copy(tampered, attestation.Signature)
+	tampered[0] ^= 0xFF
+	attestation.Signature = tampered
+
+	c := &checkpointManager{
+		consensusBackend: &fakeOracleBackend{validators: validator.AccountSet{
+			&validator.ValidatorMetadata{Address: attestation.From},
+		}},
+		attestationPool: newCheckpointAttestationPool(),
+	}
+
+	err := c.ProcessCheckpointAttestation(attestation)
+	assert.ErrorIs(t, err, errOracleInvalidSignature)
+}
@@ -0,0 +1,414 @@
+package polybft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"go.etcd.io/bbolt"
+)
+
+var (
+	// checkpointQueueBucket holds one entry per pending checkpoint, keyed by big-endian
+	// block number so bbolt's natural key ordering doubles as submission order
+	checkpointQueueBucket = []byte("checkpointQueue")
+	// checkpointMetaBucket stores small singleton values about the submission queue,
+	// currently only the last successfully submitted checkpoint block number
+	checkpointMetaBucket = []byte("checkpointQueueMeta")
+	// lastSubmittedKey is the checkpointMetaBucket key holding the last submitted block number
+	lastSubmittedKey = []byte("lastSubmitted")
+	// lastAttemptedKey is the checkpointMetaBucket key holding the last checkpoint
+	// checkpointProducer.Enqueue was asked to queue, written independently of whether the
+	// enqueue itself succeeded, so a failed enqueue can still be recovered later
+	lastAttemptedKey = []byte("lastAttempted")
+)
+
+const (
+	// checkpointSubmitterPollInterval is how often the submitter checks the queue for new work
+	checkpointSubmitterPollInterval = 2 * time.Second
+	// checkpointSubmitMaxRetries bounds the per-item retry loop before the submitter
+	// moves on and retries again on its next poll (the item stays queued either way)
+	checkpointSubmitMaxRetries = 3
+	// checkpointGasPriceBumpPercent is how much the gas price is increased on each retry
+	checkpointGasPriceBumpPercent = 20
+	// checkpointSubmitInitialBackoff is the delay before the first retry of a failed
+	// checkpoint submission; it doubles on each subsequent attempt up to checkpointSubmitMaxBackoff
+	checkpointSubmitInitialBackoff = 500 * time.Millisecond
+	// checkpointSubmitMaxBackoff caps the exponential backoff between submission retries
+	checkpointSubmitMaxBackoff = 5 * time.Second
+)
+
+// PendingCheckpoint is a crash-safe record of a block that still needs a checkpoint
+// transaction submitted for it. Only the minimal identifying information is persisted;
+// the header, extra and next validator set are re-derived from the blockchain at
+// submission time so the queue never goes stale relative to chain state
+type PendingCheckpoint struct {
+	BlockNumber  uint64
+	IsEndOfEpoch bool
+}
+
+// encodePendingCheckpoint serializes a PendingCheckpoint's value (the key is its block number)
+func encodePendingCheckpoint(p PendingCheckpoint) []byte {
+	if p.IsEndOfEpoch {
+		return []byte{1}
+	}
+
+	return []byte{0}
+}
+
+// decodePendingCheckpoint rebuilds a PendingCheckpoint from its bbolt key/value pair
+func decodePendingCheckpoint(key, value []byte) PendingCheckpoint {
+	return PendingCheckpoint{
+		BlockNumber:  binary.BigEndian.Uint64(key),
+		IsEndOfEpoch: len(value) > 0 && value[0] == 1,
+	}
+}
+
+// checkpointQueueStore persists pending checkpoints in bbolt so that submission survives restarts
+type checkpointQueueStore struct {
+	db *bbolt.DB
+}
+
+// newCheckpointQueueStore creates a checkpointQueueStore and ensures its buckets exist
+func newCheckpointQueueStore(db *bbolt.DB) (*checkpointQueueStore, error) {
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(checkpointQueueBucket); err != nil {
+			return err
+		}
+
+		_, err := tx.CreateBucketIfNotExists(checkpointMetaBucket)
+
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to initialize checkpoint queue buckets: %w", err)
+	}
+
+	return &checkpointQueueStore{db: db}, nil
+}
+
+// enqueue persists a pending checkpoint, ordered by block number
+func (s *checkpointQueueStore) enqueue(pending PendingCheckpoint) error {
+	var key [8]byte
+
+	binary.BigEndian.PutUint64(key[:], pending.BlockNumber)
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(checkpointQueueBucket).Put(key[:], encodePendingCheckpoint(pending))
+	})
+}
+
+// dequeue returns the oldest (lowest block number) pending checkpoint, if any
+func (s *checkpointQueueStore) dequeue() (*PendingCheckpoint, error) {
+	var pending *PendingCheckpoint
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		key, value := tx.Bucket(checkpointQueueBucket).Cursor().First()
+		if key == nil {
+			return nil
+		}
+
+		decoded := decodePendingCheckpoint(key, value)
+		pending = &decoded
+
+		return nil
+	})
+
+	return pending, err
+}
+
+// remove deletes a pending checkpoint once it has been successfully submitted
+func (s *checkpointQueueStore) remove(blockNumber uint64) error {
+	var key [8]byte
+
+	binary.BigEndian.PutUint64(key[:], blockNumber)
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(checkpointQueueBucket).Delete(key[:])
+	})
+}
+
+// pending returns every checkpoint currently queued for submission, in block number order
+func (s *checkpointQueueStore) pending() ([]PendingCheckpoint, error) {
+	var result []PendingCheckpoint
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(checkpointQueueBucket).ForEach(func(key, value []byte) error {
+			result = append(result, decodePendingCheckpoint(key, value))
+
+			return nil
+		})
+	})
+
+	return result, err
+}
+
+// setLastSubmitted records the block number of the most recently submitted checkpoint
+func (s *checkpointQueueStore) setLastSubmitted(blockNumber uint64) error {
+	var value [8]byte
+
+	binary.BigEndian.PutUint64(value[:], blockNumber)
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(checkpointMetaBucket).Put(lastSubmittedKey, value[:])
+	})
+}
+
+// lastSubmitted returns the block number of the most recently submitted checkpoint, or
+// zero if none has been submitted yet
+func (s *checkpointQueueStore) lastSubmitted() (uint64, error) {
+	var lastSubmitted uint64
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		value := tx.Bucket(checkpointMetaBucket).Get(lastSubmittedKey)
+		if len(value) == 8 {
+			lastSubmitted = binary.BigEndian.Uint64(value)
+		}
+
+		return nil
+	})
+
+	return lastSubmitted, err
+}
+
+// setLastAttempted records the checkpoint most recently passed to checkpointProducer.Enqueue,
+// regardless of whether that enqueue call itself succeeded
+func (s *checkpointQueueStore) setLastAttempted(pending PendingCheckpoint) error {
+	var key [8]byte
+
+	binary.BigEndian.PutUint64(key[:], pending.BlockNumber)
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(checkpointMetaBucket).Put(lastAttemptedKey, append(key[:], encodePendingCheckpoint(pending)...))
+	})
+}
+
+// lastAttempted returns the checkpoint most recently passed to checkpointProducer.Enqueue,
+// and false if none has been attempted yet
+func (s *checkpointQueueStore) lastAttempted() (PendingCheckpoint, bool, error) {
+	var (
+		pending PendingCheckpoint
+		found   bool
+	)
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		value := tx.Bucket(checkpointMetaBucket).Get(lastAttemptedKey)
+		if len(value) < 8 {
+			return nil
+		}
+
+		pending = decodePendingCheckpoint(value[:8], value[8:])
+		found = true
+
+		return nil
+	})
+
+	return pending, found, err
+}
+
+// checkpointProducer runs inline with PostBlock: it only decides whether a block is a
+// checkpoint block and enqueues it, leaving the actual rootchain submission to checkpointSubmitter
+type checkpointProducer struct {
+	queue  *checkpointQueueStore
+	logger hclog.Logger
+}
+
+// Enqueue records a checkpoint-eligible block for later submission. The attempt itself is
+// recorded first, in its own bbolt transaction, so that if the enqueue write below fails
+// (e.g. a transient bbolt error) checkpointSubmitter.reconcileLastAttempt can still notice
+// and recover the block instead of losing it for good
+func (p *checkpointProducer) Enqueue(blockNumber uint64, isEndOfEpoch bool) error {
+	pending := PendingCheckpoint{BlockNumber: blockNumber, IsEndOfEpoch: isEndOfEpoch}
+
+	if err := p.queue.setLastAttempted(pending); err != nil {
+		p.logger.Warn("failed to record checkpoint enqueue attempt", "block number", blockNumber, "error", err)
+	}
+
+	if err := p.queue.enqueue(pending); err != nil {
+		return fmt.Errorf("failed to enqueue pending checkpoint for block %d: %w", blockNumber, err)
+	}
+
+	p.logger.Debug("enqueued pending checkpoint", "block number", blockNumber)
+
+	return nil
+}
+
+// checkpointSubmitter is the consumer half of the producer/consumer split: it dequeues
+// pending checkpoints and submits them to the rootchain, one at a time, with retry and
+// gas price bumping on failure
+type checkpointSubmitter struct {
+	manager *checkpointManager
+	queue   *checkpointQueueStore
+	logger  hclog.Logger
+	closeCh chan struct{}
+}
+
+// newCheckpointSubmitter creates a checkpointSubmitter bound to the given checkpointManager
+func newCheckpointSubmitter(manager *checkpointManager, queue *checkpointQueueStore, logger hclog.Logger) *checkpointSubmitter {
+	return &checkpointSubmitter{
+		manager: manager,
+		queue:   queue,
+		logger:  logger,
+		closeCh: make(chan struct{}),
+	}
+}
+
+// run polls the queue and submits pending checkpoints until Close is called. It is meant
+// to run in its own goroutine for the lifetime of the node
+func (s *checkpointSubmitter) run() {
+	ticker := time.NewTicker(checkpointSubmitterPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.closeCh:
+			return
+		case <-ticker.C:
+			s.reconcileLastAttempt()
+			s.submitNext()
+		}
+	}
+}
+
+// reconcileLastAttempt re-enqueues the most recently attempted checkpoint if it never made
+// it into the queue and was never submitted either - the only way that can happen is the
+// enqueue's bbolt write itself failing. Without this, that single failed write would
+// permanently drop the checkpoint, since nothing else ever revisits it
+func (s *checkpointSubmitter) reconcileLastAttempt() {
+	lastAttempted, found, err := s.queue.lastAttempted()
+	if err != nil {
+		s.logger.Warn("failed to read last attempted checkpoint while reconciling", "error", err)
+
+		return
+	}
+
+	if !found {
+		return
+	}
+
+	lastSubmitted, err := s.queue.lastSubmitted()
+	if err != nil {
+		s.logger.Warn("failed to read last submitted checkpoint while reconciling", "error", err)
+
+		return
+	}
+
+	if lastAttempted.BlockNumber <= lastSubmitted {
+		return
+	}
+
+	pending, err := s.queue.pending()
+	if err != nil {
+		s.logger.Warn("failed to read pending checkpoint queue while reconciling", "error", err)
+
+		return
+	}
+
+	for _, p := range pending {
+		if p.BlockNumber == lastAttempted.BlockNumber {
+			return
+		}
+	}
+
+	if err := s.queue.enqueue(lastAttempted); err != nil {
+		s.logger.Warn("failed to recover checkpoint that failed to enqueue",
+			"block number", lastAttempted.BlockNumber, "error", err)
+
+		return
+	}
+
+	s.logger.Warn("recovered checkpoint that failed to enqueue", "block number", lastAttempted.BlockNumber)
+}
+
+// Close stops the submitter's background loop
+func (s *checkpointSubmitter) Close() {
+	close(s.closeCh)
+}
+
+// submitNext dequeues and submits (at most) a single pending checkpoint. Failures are
+// logged and the item is left queued so it is retried on the next poll
+func (s *checkpointSubmitter) submitNext() {
+	pending, err := s.queue.dequeue()
+	if err != nil {
+		s.logger.Warn("failed to read pending checkpoint queue", "error", err)
+
+		return
+	}
+
+	if pending == nil {
+		return
+	}
+
+	if err := s.submitWithRetry(*pending); err != nil {
+		s.logger.Warn("failed to submit pending checkpoint",
+			"block number", pending.BlockNumber, "error", err)
+
+		return
+	}
+
+	if err := s.queue.remove(pending.BlockNumber); err != nil {
+		s.logger.Warn("failed to remove submitted checkpoint from queue",
+			"block number", pending.BlockNumber, "error", err)
+
+		return
+	}
+
+	if err := s.queue.setLastSubmitted(pending.BlockNumber); err != nil {
+		s.logger.Warn("failed to record last submitted checkpoint",
+			"block number", pending.BlockNumber, "error", err)
+	}
+}
+
+// submitWithRetry resolves the pending checkpoint's header/extra and submits it, backing
+// off between attempts and bumping the gas price on each retry so a transaction stuck due
+// to underpricing eventually lands
+func (s *checkpointSubmitter) submitWithRetry(pending PendingCheckpoint) error {
+	header, found := s.manager.blockchain.GetHeaderByNumber(pending.BlockNumber)
+	if !found {
+		return fmt.Errorf("block %d was not found", pending.BlockNumber)
+	}
+
+	extra, err := GetIbftExtra(header.ExtraData)
+	if err != nil {
+		return err
+	}
+
+	gasPriceBump := 0
+	backoff := checkpointSubmitInitialBackoff
+
+	for attempt := 0; attempt <= checkpointSubmitMaxRetries; attempt++ {
+		err = s.manager.encodeAndSendCheckpoint(header, extra, pending.IsEndOfEpoch, gasPriceBump)
+		if err == nil {
+			return nil
+		}
+
+		if attempt == checkpointSubmitMaxRetries {
+			break
+		}
+
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > checkpointSubmitMaxBackoff {
+			backoff = checkpointSubmitMaxBackoff
+		}
+
+		gasPriceBump += checkpointGasPriceBumpPercent
+	}
+
+	return err
+}
+
+// Pending returns every checkpoint currently queued for submission, ordered by block
+// number. It is not yet exposed over any RPC surface - callers are limited to this
+// package and its tests - but is kept public so a future operator-facing endpoint can
+// wrap it without needing to reach into the queue store directly
+func (c *checkpointManager) Pending() ([]PendingCheckpoint, error) {
+	return c.queue.pending()
+}
+
+// LastSubmitted returns the block number of the most recently submitted checkpoint. Like
+// Pending, it is not yet exposed over any RPC surface
+func (c *checkpointManager) LastSubmitted() (uint64, error) {
+	return c.queue.lastSubmitted()
+}
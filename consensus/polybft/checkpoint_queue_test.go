@@ -0,0 +1,108 @@
+package polybft
+
+import (
+	"path/filepath"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func newTestCheckpointQueueStore(t *testing.T) *checkpointQueueStore {
+	t.Helper()
+
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "checkpoint_queue_test.db"), 0666, nil)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	store, err := newCheckpointQueueStore(db)
+	require.NoError(t, err)
+
+	return store
+}
+
+func TestCheckpointQueueStore_PendingOrderedByBlockNumber(t *testing.T) {
+	store := newTestCheckpointQueueStore(t)
+
+	require.NoError(t, store.enqueue(PendingCheckpoint{BlockNumber: 30, IsEndOfEpoch: true}))
+	require.NoError(t, store.enqueue(PendingCheckpoint{BlockNumber: 10}))
+	require.NoError(t, store.enqueue(PendingCheckpoint{BlockNumber: 20}))
+
+	pending, err := store.pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 3)
+
+	assert.Equal(t, uint64(10), pending[0].BlockNumber)
+	assert.Equal(t, uint64(20), pending[1].BlockNumber)
+	assert.Equal(t, uint64(30), pending[2].BlockNumber)
+	assert.True(t, pending[2].IsEndOfEpoch)
+}
+
+func TestCheckpointQueueStore_DequeueAndRemove(t *testing.T) {
+	store := newTestCheckpointQueueStore(t)
+
+	require.NoError(t, store.enqueue(PendingCheckpoint{BlockNumber: 5}))
+	require.NoError(t, store.enqueue(PendingCheckpoint{BlockNumber: 15}))
+
+	next, err := store.dequeue()
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.Equal(t, uint64(5), next.BlockNumber)
+
+	require.NoError(t, store.remove(next.BlockNumber))
+
+	next, err = store.dequeue()
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.Equal(t, uint64(15), next.BlockNumber)
+}
+
+func TestCheckpointQueueStore_LastSubmitted(t *testing.T) {
+	store := newTestCheckpointQueueStore(t)
+
+	lastSubmitted, err := store.lastSubmitted()
+	require.NoError(t, err)
+	assert.Zero(t, lastSubmitted)
+
+	require.NoError(t, store.setLastSubmitted(42))
+
+	lastSubmitted, err = store.lastSubmitted()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), lastSubmitted)
+}
+
+func TestCheckpointQueueStore_LastAttempted(t *testing.T) {
+	store := newTestCheckpointQueueStore(t)
+
+	_, found, err := store.lastAttempted()
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, store.setLastAttempted(PendingCheckpoint{BlockNumber: 42, IsEndOfEpoch: true}))
+
+	lastAttempted, found, err := store.lastAttempted()
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, uint64(42), lastAttempted.BlockNumber)
+	assert.True(t, lastAttempted.IsEndOfEpoch)
+}
+
+func TestCheckpointProducer_EnqueueRecordsAttemptAndQueuesCheckpoint(t *testing.T) {
+	store := newTestCheckpointQueueStore(t)
+	producer := &checkpointProducer{queue: store, logger: hclog.NewNullLogger()}
+
+	require.NoError(t, producer.Enqueue(7, false))
+
+	lastAttempted, found, err := store.lastAttempted()
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, uint64(7), lastAttempted.BlockNumber)
+
+	pending, err := store.pending()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, uint64(7), pending[0].BlockNumber)
+}
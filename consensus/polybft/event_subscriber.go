@@ -0,0 +1,125 @@
+package polybft
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/umbracle/ethgo"
+	"go.etcd.io/bbolt"
+
+	"github.com/0xPolygon/polygon-edge/types"
+)
+
+const (
+	// eventDispatchMaxRetries bounds the number of attempts the dispatcher makes to commit
+	// a block's events to boltDb before giving up and surfacing the error to the caller
+	eventDispatchMaxRetries = 5
+	// eventDispatchInitialBackoff is the delay before the first retry; it doubles on each
+	// subsequent attempt up to eventDispatchMaxBackoff
+	eventDispatchInitialBackoff = 100 * time.Millisecond
+	// eventDispatchMaxBackoff caps the exponential backoff between retries
+	eventDispatchMaxBackoff = 2 * time.Second
+)
+
+// EventSubscriber is implemented by components that need to observe logs emitted in
+// finalized blocks (e.g. checkpointManager watching exit and slashed events). Subscribers
+// are fanned events from a single eventDispatcher instead of each polling blocks on their own
+type EventSubscriber interface {
+	// GetLogFilters returns the set of (contract address -> event signatures) this
+	// subscriber is interested in. The dispatcher only calls ProcessLog for matching logs
+	GetLogFilters() map[types.Address][]types.Hash
+	// ProcessLog handles a single matching log, participating in the caller's bbolt
+	// transaction so that all subscribers observing the same block commit atomically
+	ProcessLog(header *types.Header, log *ethgo.Log, dbTx *bbolt.Tx) error
+}
+
+// eventDispatcher fans logs from finalized blocks out to every registered EventSubscriber,
+// running all of their ProcessLog calls for a given block inside a single bbolt transaction
+type eventDispatcher struct {
+	db          *bbolt.DB
+	subscribers []EventSubscriber
+	// filters caches GetLogFilters results per subscriber to avoid recomputing it per block
+	filters []map[types.Address][]types.Hash
+}
+
+// newEventDispatcher creates an eventDispatcher backed by the given bbolt database
+func newEventDispatcher(db *bbolt.DB) *eventDispatcher {
+	return &eventDispatcher{db: db}
+}
+
+// RegisterSubscriber adds a subscriber to the fan-out list. It is not safe to call
+// concurrently with Dispatch and is expected to happen once, at startup
+func (d *eventDispatcher) RegisterSubscriber(subscriber EventSubscriber) {
+	d.subscribers = append(d.subscribers, subscriber)
+	d.filters = append(d.filters, subscriber.GetLogFilters())
+}
+
+// isSubscribed returns true if the given subscriber registered for this log's address and topic
+func isSubscribed(filters map[types.Address][]types.Hash, log *ethgo.Log) bool {
+	topics, ok := filters[types.Address(log.Address)]
+	if !ok {
+		return false
+	}
+
+	if len(log.Topics) == 0 {
+		return false
+	}
+
+	for _, topic := range topics {
+		if types.Hash(log.Topics[0]) == topic {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Dispatch delivers every log of the given block to its subscribed EventSubscribers, all
+// within a single bbolt write transaction, so that a crash mid-block can never leave one
+// subscriber's state updated while another's is not
+func (d *eventDispatcher) Dispatch(header *types.Header, logs []*ethgo.Log) error {
+	return d.db.Update(func(tx *bbolt.Tx) error {
+		for _, log := range logs {
+			for i, subscriber := range d.subscribers {
+				if !isSubscribed(d.filters[i], log) {
+					continue
+				}
+
+				if err := subscriber.ProcessLog(header, log, tx); err != nil {
+					return fmt.Errorf("subscriber failed to process log (block %d, tx %s): %w",
+						header.Number, log.TransactionHash, err)
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// DispatchWithRetry behaves like Dispatch but retries transient bbolt failures with a
+// bounded exponential backoff instead of letting a single failed write silently drop events
+func (d *eventDispatcher) DispatchWithRetry(header *types.Header, logs []*ethgo.Log) error {
+	backoff := eventDispatchInitialBackoff
+
+	var err error
+
+	for attempt := 0; attempt < eventDispatchMaxRetries; attempt++ {
+		if err = d.Dispatch(header, logs); err == nil {
+			return nil
+		}
+
+		if attempt == eventDispatchMaxRetries-1 {
+			break
+		}
+
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > eventDispatchMaxBackoff {
+			backoff = eventDispatchMaxBackoff
+		}
+	}
+
+	return fmt.Errorf("failed to dispatch events for block %d after %d attempts: %w",
+		header.Number, eventDispatchMaxRetries, err)
+}
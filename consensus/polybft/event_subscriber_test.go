@@ -0,0 +1,128 @@
+package polybft
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo"
+	"go.etcd.io/bbolt"
+
+	"github.com/0xPolygon/polygon-edge/types"
+)
+
+// fakeSubscriber is a minimal EventSubscriber that records every log it's handed and can be
+// configured to fail its first N calls, to exercise DispatchWithRetry
+type fakeSubscriber struct {
+	filters      map[types.Address][]types.Hash
+	processed    []*ethgo.Log
+	failuresLeft int
+}
+
+func (f *fakeSubscriber) GetLogFilters() map[types.Address][]types.Hash {
+	return f.filters
+}
+
+func (f *fakeSubscriber) ProcessLog(header *types.Header, log *ethgo.Log, dbTx *bbolt.Tx) error {
+	if f.failuresLeft > 0 {
+		f.failuresLeft--
+
+		return errors.New("simulated transient failure")
+	}
+
+	f.processed = append(f.processed, log)
+
+	return nil
+}
+
+func newTestEventDispatcher(t *testing.T) *eventDispatcher {
+	t.Helper()
+
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "event_dispatcher_test.db"), 0666, nil)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	return newEventDispatcher(db)
+}
+
+func TestEventDispatcher_OnlyDeliversMatchingLogs(t *testing.T) {
+	dispatcher := newTestEventDispatcher(t)
+
+	contractAddr := types.Address{1}
+	topic := types.Hash{2}
+
+	subscriber := &fakeSubscriber{filters: map[types.Address][]types.Hash{contractAddr: {topic}}}
+	dispatcher.RegisterSubscriber(subscriber)
+
+	matching := &ethgo.Log{Address: ethgo.Address(contractAddr), Topics: []ethgo.Hash{ethgo.Hash(topic)}}
+	other := &ethgo.Log{Address: ethgo.Address(types.Address{9}), Topics: []ethgo.Hash{ethgo.Hash(topic)}}
+
+	err := dispatcher.Dispatch(&types.Header{Number: 1}, []*ethgo.Log{matching, other})
+	require.NoError(t, err)
+
+	require.Len(t, subscriber.processed, 1)
+	assert.Equal(t, matching, subscriber.processed[0])
+}
+
+func TestEventDispatcher_FansOutToEverySubscriber(t *testing.T) {
+	dispatcher := newTestEventDispatcher(t)
+
+	contractAddr := types.Address{1}
+	topic := types.Hash{2}
+	filters := map[types.Address][]types.Hash{contractAddr: {topic}}
+
+	subscriberA := &fakeSubscriber{filters: filters}
+	subscriberB := &fakeSubscriber{filters: filters}
+	dispatcher.RegisterSubscriber(subscriberA)
+	dispatcher.RegisterSubscriber(subscriberB)
+
+	log := &ethgo.Log{Address: ethgo.Address(contractAddr), Topics: []ethgo.Hash{ethgo.Hash(topic)}}
+
+	err := dispatcher.Dispatch(&types.Header{Number: 1}, []*ethgo.Log{log})
+	require.NoError(t, err)
+
+	assert.Len(t, subscriberA.processed, 1)
+	assert.Len(t, subscriberB.processed, 1)
+}
+
+func TestEventDispatcher_DispatchWithRetrySucceedsAfterTransientFailures(t *testing.T) {
+	dispatcher := newTestEventDispatcher(t)
+
+	contractAddr := types.Address{1}
+	topic := types.Hash{2}
+
+	subscriber := &fakeSubscriber{
+		filters:      map[types.Address][]types.Hash{contractAddr: {topic}},
+		failuresLeft: 2,
+	}
+	dispatcher.RegisterSubscriber(subscriber)
+
+	log := &ethgo.Log{Address: ethgo.Address(contractAddr), Topics: []ethgo.Hash{ethgo.Hash(topic)}}
+
+	err := dispatcher.DispatchWithRetry(&types.Header{Number: 1}, []*ethgo.Log{log})
+	require.NoError(t, err)
+	require.Len(t, subscriber.processed, 1)
+	assert.Equal(t, log, subscriber.processed[0])
+}
+
+func TestEventDispatcher_DispatchWithRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	dispatcher := newTestEventDispatcher(t)
+
+	contractAddr := types.Address{1}
+	topic := types.Hash{2}
+
+	subscriber := &fakeSubscriber{
+		filters:      map[types.Address][]types.Hash{contractAddr: {topic}},
+		failuresLeft: eventDispatchMaxRetries,
+	}
+	dispatcher.RegisterSubscriber(subscriber)
+
+	log := &ethgo.Log{Address: ethgo.Address(contractAddr), Topics: []ethgo.Hash{ethgo.Hash(topic)}}
+
+	err := dispatcher.DispatchWithRetry(&types.Header{Number: 1}, []*ethgo.Log{log})
+	require.Error(t, err)
+	assert.Empty(t, subscriber.processed)
+}
@@ -0,0 +1,49 @@
+package polybft
+
+import (
+	"fmt"
+
+	"github.com/umbracle/ethgo"
+
+	"github.com/0xPolygon/polygon-edge/consensus/polybft/contractsapi"
+)
+
+// ExitEvent is a single L2StateSynced exit event observed on L2, enriched with the epoch and
+// block number it was recorded in so it can later be grouped with the other exit events that
+// share a checkpoint's exit tree
+type ExitEvent struct {
+	*contractsapi.L2StateSyncedEvent
+	ID          uint64
+	EpochNumber uint64
+	BlockNumber uint64
+}
+
+// Sig returns the L2StateSynced event signature ExitEvent logs are matched against
+func (e *ExitEvent) Sig() ethgo.Hash {
+	var event contractsapi.L2StateSyncedEvent
+
+	return event.Sig()
+}
+
+// decodeExitEvent parses log into an ExitEvent, stamping it with the epoch and block number
+// it belongs to. For exits emitted in an epoch-ending block these are the next epoch and
+// block - see parseEvent, which is what actually resolves them
+func decodeExitEvent(log *ethgo.Log, epoch, blockNumber uint64) (*ExitEvent, error) {
+	var event contractsapi.L2StateSyncedEvent
+
+	matches, err := event.ParseLog(log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse exit event log: %w", err)
+	}
+
+	if !matches {
+		return nil, fmt.Errorf("log does not match the L2StateSynced event signature")
+	}
+
+	return &ExitEvent{
+		L2StateSyncedEvent: &event,
+		ID:                 event.ID.Uint64(),
+		EpochNumber:        epoch,
+		BlockNumber:        blockNumber,
+	}, nil
+}
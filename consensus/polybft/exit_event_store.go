@@ -0,0 +1,240 @@
+package polybft
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+)
+
+var (
+	// exitEventsBucket holds one entry per exit event, keyed by big-endian exit ID
+	exitEventsBucket = []byte("exitEvents")
+	// exitEventsByEpochBucket indexes exit IDs by the epoch they were recorded in
+	exitEventsByEpochBucket = []byte("exitEventsByEpoch")
+	// pendingSlashExitsBucket holds the IDs of exit events that a SlashedEvent marked as
+	// awaiting a slash exit proof
+	pendingSlashExitsBucket = []byte("pendingSlashExits")
+	// exitEventMetaBucket stores small singleton values about exit event ingestion
+	exitEventMetaBucket = []byte("exitEventsMeta")
+	// lastSavedBlockKey is the exitEventMetaBucket key holding the last block whose exit
+	// events were committed to this store
+	lastSavedBlockKey = []byte("lastSavedBlock")
+)
+
+// State aggregates a node's persistent, bbolt-backed stores
+type State struct {
+	ExitEventStore *exitEventStore
+}
+
+// newState creates a State backed by db, initializing every store it owns
+func newState(db *bbolt.DB) (*State, error) {
+	exitEventStore, err := newExitEventStore(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &State{ExitEventStore: exitEventStore}, nil
+}
+
+// exitEventStore persists exit events and their checkpoint/slash bookkeeping in bbolt
+type exitEventStore struct {
+	db *bbolt.DB
+}
+
+// newExitEventStore creates an exitEventStore and ensures its buckets exist
+func newExitEventStore(db *bbolt.DB) (*exitEventStore, error) {
+	buckets := [][]byte{exitEventsBucket, exitEventsByEpochBucket, pendingSlashExitsBucket, exitEventMetaBucket}
+
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		for _, bucket := range buckets {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to initialize exit event buckets: %w", err)
+	}
+
+	return &exitEventStore{db: db}, nil
+}
+
+// exitIDKey big-endian encodes an exit ID so bbolt's natural key ordering doubles as ID order
+func exitIDKey(exitID uint64) []byte {
+	var key [8]byte
+
+	binary.BigEndian.PutUint64(key[:], exitID)
+
+	return key[:]
+}
+
+// epochKey big-endian encodes an epoch number for use as a bucket key
+func epochKey(epoch uint64) []byte {
+	var key [8]byte
+
+	binary.BigEndian.PutUint64(key[:], epoch)
+
+	return key[:]
+}
+
+// encodeExitIDs packs a slice of exit IDs into a flat byte slice
+func encodeExitIDs(ids []uint64) []byte {
+	buf := make([]byte, len(ids)*8)
+
+	for i, id := range ids {
+		binary.BigEndian.PutUint64(buf[i*8:], id)
+	}
+
+	return buf
+}
+
+// decodeExitIDs unpacks a byte slice produced by encodeExitIDs
+func decodeExitIDs(data []byte) ([]uint64, error) {
+	if len(data)%8 != 0 {
+		return nil, fmt.Errorf("corrupt exit id index: length %d is not a multiple of 8", len(data))
+	}
+
+	ids := make([]uint64, len(data)/8)
+
+	for i := range ids {
+		ids[i] = binary.BigEndian.Uint64(data[i*8:])
+	}
+
+	return ids, nil
+}
+
+// insertExitEvents stores each exit event, indexed by both its ID and its epoch, as part of
+// the caller's bbolt transaction. Exit events are encoded as JSON rather than this package's
+// usual hand-rolled binary layout, since ExitEvent embeds the ABI-derived
+// contractsapi.L2StateSyncedEvent, whose field layout isn't this store's to hand a custom
+// codec for
+func (s *exitEventStore) insertExitEvents(events []*ExitEvent, tx *bbolt.Tx) error {
+	eventsBucket := tx.Bucket(exitEventsBucket)
+	epochBucket := tx.Bucket(exitEventsByEpochBucket)
+
+	for _, event := range events {
+		encoded, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode exit event (ID=%d): %w", event.ID, err)
+		}
+
+		if err := eventsBucket.Put(exitIDKey(event.ID), encoded); err != nil {
+			return err
+		}
+
+		epochIDs, err := decodeExitIDs(epochBucket.Get(epochKey(event.EpochNumber)))
+		if err != nil {
+			return fmt.Errorf("failed to decode exit id index for epoch %d: %w", event.EpochNumber, err)
+		}
+
+		epochIDs = append(epochIDs, event.ID)
+
+		if err := epochBucket.Put(epochKey(event.EpochNumber), encodeExitIDs(epochIDs)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateLastSaved records the last block whose exit events were committed to this store
+func (s *exitEventStore) updateLastSaved(blockNumber uint64, tx *bbolt.Tx) error {
+	var value [8]byte
+
+	binary.BigEndian.PutUint64(value[:], blockNumber)
+
+	return tx.Bucket(exitEventMetaBucket).Put(lastSavedBlockKey, value[:])
+}
+
+// removeSlashExitEvents marks exitID as awaiting a slash exit proof, once its SlashedEvent log
+// is observed. The exit event itself stays in the store and in its epoch's index
+func (s *exitEventStore) removeSlashExitEvents(tx *bbolt.Tx, exitID uint64) error {
+	return tx.Bucket(pendingSlashExitsBucket).Put(exitIDKey(exitID), []byte{1})
+}
+
+// getExitEvent returns a single exit event by ID
+func (s *exitEventStore) getExitEvent(exitID uint64) (*ExitEvent, error) {
+	var event *ExitEvent
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		value := tx.Bucket(exitEventsBucket).Get(exitIDKey(exitID))
+		if value == nil {
+			return fmt.Errorf("exit event (ID=%d) not found", exitID)
+		}
+
+		event = &ExitEvent{}
+
+		return json.Unmarshal(value, event)
+	})
+
+	return event, err
+}
+
+// getExitEventsByEpoch returns every exit event recorded for the given epoch
+func (s *exitEventStore) getExitEventsByEpoch(epoch uint64) ([]*ExitEvent, error) {
+	var events []*ExitEvent
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		ids, err := decodeExitIDs(tx.Bucket(exitEventsByEpochBucket).Get(epochKey(epoch)))
+		if err != nil {
+			return fmt.Errorf("failed to decode exit id index for epoch %d: %w", epoch, err)
+		}
+
+		eventsBucket := tx.Bucket(exitEventsBucket)
+
+		for _, id := range ids {
+			value := eventsBucket.Get(exitIDKey(id))
+			if value == nil {
+				continue
+			}
+
+			event := &ExitEvent{}
+			if err := json.Unmarshal(value, event); err != nil {
+				return fmt.Errorf("failed to decode exit event (ID=%d): %w", id, err)
+			}
+
+			events = append(events, event)
+		}
+
+		return nil
+	})
+
+	return events, err
+}
+
+// getExitEventsForProof returns every exit event checkpointed together at (epoch,
+// checkpointBlock): the exit events of epoch recorded at or before checkpointBlock
+func (s *exitEventStore) getExitEventsForProof(epoch, checkpointBlock uint64) ([]*ExitEvent, error) {
+	events, err := s.getExitEventsByEpoch(epoch)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpointed := make([]*ExitEvent, 0, len(events))
+
+	for _, event := range events {
+		if event.BlockNumber <= checkpointBlock {
+			checkpointed = append(checkpointed, event)
+		}
+	}
+
+	return checkpointed, nil
+}
+
+// getPendingSlashExitIDs returns the IDs of exit events awaiting a slash exit proof
+func (s *exitEventStore) getPendingSlashExitIDs() ([]uint64, error) {
+	var ids []uint64
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(pendingSlashExitsBucket).ForEach(func(k, v []byte) error {
+			ids = append(ids, binary.BigEndian.Uint64(k))
+
+			return nil
+		})
+	})
+
+	return ids, err
+}
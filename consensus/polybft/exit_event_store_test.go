@@ -0,0 +1,86 @@
+package polybft
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+func newTestExitEventStore(t *testing.T) *exitEventStore {
+	t.Helper()
+
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "exit_event_test.db"), 0666, nil)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	store, err := newExitEventStore(db)
+	require.NoError(t, err)
+
+	return store
+}
+
+func insertTestExitEvents(t *testing.T, store *exitEventStore, events ...*ExitEvent) {
+	t.Helper()
+
+	require.NoError(t, store.db.Update(func(tx *bbolt.Tx) error {
+		return store.insertExitEvents(events, tx)
+	}))
+}
+
+func TestExitEventStore_GetExitEventMissing(t *testing.T) {
+	store := newTestExitEventStore(t)
+
+	_, err := store.getExitEvent(1)
+	assert.Error(t, err)
+}
+
+func TestExitEventStore_InsertAndGetExitEvent(t *testing.T) {
+	store := newTestExitEventStore(t)
+
+	insertTestExitEvents(t, store, &ExitEvent{ID: 1, EpochNumber: 5, BlockNumber: 100})
+
+	event, err := store.getExitEvent(1)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), event.EpochNumber)
+	assert.Equal(t, uint64(100), event.BlockNumber)
+}
+
+func TestExitEventStore_GetExitEventsByEpoch(t *testing.T) {
+	store := newTestExitEventStore(t)
+
+	insertTestExitEvents(t, store,
+		&ExitEvent{ID: 1, EpochNumber: 5, BlockNumber: 100},
+		&ExitEvent{ID: 2, EpochNumber: 5, BlockNumber: 100},
+		&ExitEvent{ID: 3, EpochNumber: 6, BlockNumber: 110})
+
+	events, err := store.getExitEventsByEpoch(5)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, uint64(1), events[0].ID)
+	assert.Equal(t, uint64(2), events[1].ID)
+
+	events, err = store.getExitEventsByEpoch(6)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, uint64(3), events[0].ID)
+}
+
+func TestExitEventStore_PendingSlashExitIDs(t *testing.T) {
+	store := newTestExitEventStore(t)
+
+	ids, err := store.getPendingSlashExitIDs()
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	require.NoError(t, store.db.Update(func(tx *bbolt.Tx) error {
+		return store.removeSlashExitEvents(tx, 42)
+	}))
+
+	ids, err = store.getPendingSlashExitIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{42}, ids)
+}
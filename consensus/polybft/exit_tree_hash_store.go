@@ -0,0 +1,83 @@
+package polybft
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/0xPolygon/polygon-edge/merkle-tree"
+)
+
+// exitTreeHashAlgoBucket records, per epoch, which merkle.HashAlgorithm was used to build
+// that epoch's exit tree. An operator is free to change the configured algorithm between
+// restarts, but exit proofs for an already-checkpointed epoch must keep using whatever
+// algorithm actually produced the root committed on-chain for it
+var exitTreeHashAlgoBucket = []byte("exitTreeHashAlgo")
+
+// exitTreeHashAlgoStore persists the exit tree hash algorithm used for each epoch, so it
+// survives restarts and config changes. It is bbolt-backed, local to this node, and shares
+// the node's state db. Once an epoch is actually checkpointed on-chain, its authoritative
+// algorithm record becomes that checkpoint block's Extra.Checkpoint.HashAlgorithm - readable
+// and verifiable by any node - and hashFuncForEpoch prefers it over this store; this store
+// only remains load-bearing for an epoch that hasn't been checkpointed yet
+type exitTreeHashAlgoStore struct {
+	db *bbolt.DB
+}
+
+// newExitTreeHashAlgoStore creates an exitTreeHashAlgoStore and ensures its bucket exists
+func newExitTreeHashAlgoStore(db *bbolt.DB) (*exitTreeHashAlgoStore, error) {
+	if err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(exitTreeHashAlgoBucket)
+
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to initialize exit tree hash algorithm bucket: %w", err)
+	}
+
+	return &exitTreeHashAlgoStore{db: db}, nil
+}
+
+// recordIfAbsent records algorithm as the hash algorithm used for epoch, unless one was
+// already recorded for it - the first algorithm an epoch's exit tree was actually built
+// with is the one its on-chain root is permanently tied to, and must never be overwritten
+func (s *exitTreeHashAlgoStore) recordIfAbsent(epoch uint64, algorithm merkle.HashAlgorithm) error {
+	var key [8]byte
+
+	binary.BigEndian.PutUint64(key[:], epoch)
+
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(exitTreeHashAlgoBucket)
+
+		if bucket.Get(key[:]) != nil {
+			return nil
+		}
+
+		return bucket.Put(key[:], []byte{byte(algorithm)})
+	})
+}
+
+// get returns the hash algorithm recorded for epoch, and false if none was ever recorded
+// (e.g. the epoch predates this feature, or its exit tree was never built on this node)
+func (s *exitTreeHashAlgoStore) get(epoch uint64) (merkle.HashAlgorithm, bool, error) {
+	var (
+		key       [8]byte
+		algorithm merkle.HashAlgorithm
+		found     bool
+	)
+
+	binary.BigEndian.PutUint64(key[:], epoch)
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		value := tx.Bucket(exitTreeHashAlgoBucket).Get(key[:])
+		if len(value) != 1 {
+			return nil
+		}
+
+		algorithm, found = merkle.HashAlgorithm(value[0]), true
+
+		return nil
+	})
+
+	return algorithm, found, err
+}
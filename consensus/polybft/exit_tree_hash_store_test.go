@@ -0,0 +1,46 @@
+package polybft
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+
+	merkle "github.com/0xPolygon/polygon-edge/merkle-tree"
+)
+
+func newTestExitTreeHashAlgoStore(t *testing.T) *exitTreeHashAlgoStore {
+	t.Helper()
+
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "exit_tree_hash_algo_test.db"), 0666, nil)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	store, err := newExitTreeHashAlgoStore(db)
+	require.NoError(t, err)
+
+	return store
+}
+
+func TestExitTreeHashAlgoStore_GetMissingEpoch(t *testing.T) {
+	store := newTestExitTreeHashAlgoStore(t)
+
+	_, found, err := store.get(7)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestExitTreeHashAlgoStore_RecordIfAbsentNeverOverwrites(t *testing.T) {
+	store := newTestExitTreeHashAlgoStore(t)
+
+	require.NoError(t, store.recordIfAbsent(7, merkle.HashAlgorithmKeccak256))
+	require.NoError(t, store.recordIfAbsent(7, merkle.HashAlgorithmPoseidon))
+
+	algorithm, found, err := store.get(7)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, merkle.HashAlgorithmKeccak256, algorithm)
+}
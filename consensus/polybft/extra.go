@@ -0,0 +1,268 @@
+package polybft
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/umbracle/fastrlp"
+
+	"github.com/0xPolygon/polygon-edge/consensus/polybft/validator"
+	"github.com/0xPolygon/polygon-edge/merkle-tree"
+	"github.com/0xPolygon/polygon-edge/types"
+)
+
+// ExtraVanity is the fixed-length prefix reserved ahead of the RLP-encoded Extra in a
+// block header's ExtraData
+const ExtraVanity = 32
+
+// errExtraTooShort is returned when a header's ExtraData is too short to even hold the vanity prefix
+var errExtraTooShort = errors.New("extra data is shorter than the vanity prefix")
+
+// Extra is the IBFT-specific data carried in a block header's ExtraData, after the vanity prefix
+type Extra struct {
+	// Checkpoint carries the data this block commits to the rootchain checkpoint. It is nil
+	// for blocks that aren't checkpoint blocks
+	Checkpoint *CheckpointData
+	// Validators carries the validator set delta applied by this block. It is non-nil only
+	// for epoch-ending blocks
+	Validators *validator.ValidatorSetDelta
+	// Committed carries the aggregated BLS signature and signer bitmap the proposer collected
+	// for this block from the previous block's validator set
+	Committed *Signature
+}
+
+// Signature is an aggregated BLS signature over a block, together with a bitmap identifying
+// which validators from the signing set contributed to it
+type Signature struct {
+	AggregatedSignature []byte
+	Bitmap              []byte
+}
+
+// CheckpointData is the portion of a block's Extra that a checkpoint (BLS-aggregate or
+// oracle) submission is built from
+type CheckpointData struct {
+	// BlockRound is the consensus round this block was finalized in
+	BlockRound uint64
+	// EpochNumber is the epoch this block belongs to
+	EpochNumber uint64
+	// CurrentValidatorsHash is the hash of the validator set active for this epoch
+	CurrentValidatorsHash types.Hash
+	// EventRoot is the root of this epoch's exit event merkle tree, as returned by
+	// checkpointManager.BuildEventRoot
+	EventRoot types.Hash
+	// HashAlgorithm identifies which merkle.HashAlgorithm EventRoot was built with.
+	// It is RLP-optional: blocks checkpointed before this field existed simply omit it,
+	// and decoding such a block defaults HashAlgorithm to merkle.HashAlgorithmKeccak256 -
+	// the only algorithm that existed at the time. Recording it here, rather than only in
+	// each node's local exitTreeHashAlgo store, is what lets a light client or any node
+	// that never built the tree itself verify an exit proof without trusting the
+	// submitter's choice of algorithm out of band
+	HashAlgorithm merkle.HashAlgorithm
+}
+
+// GetIbftExtra decodes the Extra RLP-encoded after the vanity prefix in a block header's ExtraData
+func GetIbftExtra(extraData []byte) (*Extra, error) {
+	if len(extraData) < ExtraVanity {
+		return nil, errExtraTooShort
+	}
+
+	extra := &Extra{}
+	if err := extra.UnmarshalRLP(extraData[ExtraVanity:]); err != nil {
+		return nil, fmt.Errorf("failed to RLP-decode header extra data: %w", err)
+	}
+
+	return extra, nil
+}
+
+// MarshalRLPTo marshals e into dst, appending to whatever dst already holds
+func (e *Extra) MarshalRLPTo(dst []byte) []byte {
+	ar := &fastrlp.Arena{}
+
+	return e.MarshalRLPWith(ar).MarshalTo(dst)
+}
+
+// MarshalRLPWith marshals e using ar, returning the resulting RLP value
+func (e *Extra) MarshalRLPWith(ar *fastrlp.Arena) *fastrlp.Value {
+	vv := ar.NewArray()
+
+	if e.Validators == nil {
+		vv.Set(ar.NewNullArray())
+	} else {
+		vv.Set(e.Validators.MarshalRLPWith(ar))
+	}
+
+	if e.Committed == nil {
+		vv.Set(ar.NewNullArray())
+	} else {
+		vv.Set(e.Committed.MarshalRLPWith(ar))
+	}
+
+	if e.Checkpoint == nil {
+		vv.Set(ar.NewNullArray())
+	} else {
+		vv.Set(e.Checkpoint.MarshalRLPWith(ar))
+	}
+
+	return vv
+}
+
+// MarshalRLPWith marshals s using ar, returning the resulting RLP value
+func (s *Signature) MarshalRLPWith(ar *fastrlp.Arena) *fastrlp.Value {
+	vv := ar.NewArray()
+
+	vv.Set(ar.NewBytes(s.AggregatedSignature))
+	vv.Set(ar.NewBytes(s.Bitmap))
+
+	return vv
+}
+
+// MarshalRLPWith marshals c using ar, returning the resulting RLP value. HashAlgorithm is
+// always written as the array's 5th element - UnmarshalRLPWith treats its absence (for
+// data encoded before this field existed) as merkle.HashAlgorithmKeccak256
+func (c *CheckpointData) MarshalRLPWith(ar *fastrlp.Arena) *fastrlp.Value {
+	vv := ar.NewArray()
+
+	vv.Set(ar.NewUint(c.BlockRound))
+	vv.Set(ar.NewUint(c.EpochNumber))
+	vv.Set(ar.NewBytes(c.CurrentValidatorsHash.Bytes()))
+	vv.Set(ar.NewBytes(c.EventRoot.Bytes()))
+	vv.Set(ar.NewUint(uint64(c.HashAlgorithm)))
+
+	return vv
+}
+
+// UnmarshalRLP unmarshals input into e
+func (e *Extra) UnmarshalRLP(input []byte) error {
+	p := &fastrlp.Parser{}
+
+	v, err := p.Parse(input)
+	if err != nil {
+		return err
+	}
+
+	return e.UnmarshalRLPWith(v)
+}
+
+// UnmarshalRLPWith unmarshals v into e
+func (e *Extra) UnmarshalRLPWith(v *fastrlp.Value) error {
+	elems, err := v.GetElems()
+	if err != nil {
+		return fmt.Errorf("failed to decode extra data array: %w", err)
+	}
+
+	if len(elems) == 0 {
+		return nil
+	}
+
+	if len(elems) > 0 {
+		if subElems, err := elems[0].GetElems(); err == nil && len(subElems) > 0 {
+			validators := &validator.ValidatorSetDelta{}
+			if err := validators.UnmarshalRLPWith(elems[0]); err != nil {
+				return fmt.Errorf("failed to decode extra validators: %w", err)
+			}
+
+			e.Validators = validators
+		}
+	}
+
+	if len(elems) > 1 {
+		if subElems, err := elems[1].GetElems(); err == nil && len(subElems) > 0 {
+			committed := &Signature{}
+			if err := committed.UnmarshalRLPWith(elems[1]); err != nil {
+				return fmt.Errorf("failed to decode extra committed signature: %w", err)
+			}
+
+			e.Committed = committed
+		}
+	}
+
+	if len(elems) > 2 {
+		if subElems, err := elems[2].GetElems(); err == nil && len(subElems) > 0 {
+			checkpoint := &CheckpointData{}
+			if err := checkpoint.UnmarshalRLPWith(elems[2]); err != nil {
+				return err
+			}
+
+			e.Checkpoint = checkpoint
+		}
+	}
+
+	return nil
+}
+
+// UnmarshalRLPWith unmarshals v into s
+func (s *Signature) UnmarshalRLPWith(v *fastrlp.Value) error {
+	elems, err := v.GetElems()
+	if err != nil {
+		return fmt.Errorf("failed to decode committed signature array: %w", err)
+	}
+
+	if len(elems) < 2 {
+		return fmt.Errorf("committed signature array too short: expected at least 2 elements, got %d", len(elems))
+	}
+
+	aggregatedSignature, err := elems[0].GetBytes(nil)
+	if err != nil {
+		return fmt.Errorf("failed to decode aggregated signature: %w", err)
+	}
+
+	bitmap, err := elems[1].GetBytes(nil)
+	if err != nil {
+		return fmt.Errorf("failed to decode signer bitmap: %w", err)
+	}
+
+	s.AggregatedSignature = aggregatedSignature
+	s.Bitmap = bitmap
+
+	return nil
+}
+
+// UnmarshalRLPWith unmarshals v into c
+func (c *CheckpointData) UnmarshalRLPWith(v *fastrlp.Value) error {
+	elems, err := v.GetElems()
+	if err != nil {
+		return fmt.Errorf("failed to decode checkpoint data array: %w", err)
+	}
+
+	if len(elems) < 4 {
+		return fmt.Errorf("checkpoint data array too short: expected at least 4 elements, got %d", len(elems))
+	}
+
+	blockRound, err := elems[0].GetUint64()
+	if err != nil {
+		return fmt.Errorf("failed to decode checkpoint block round: %w", err)
+	}
+
+	epochNumber, err := elems[1].GetUint64()
+	if err != nil {
+		return fmt.Errorf("failed to decode checkpoint epoch number: %w", err)
+	}
+
+	currentValidatorsHash, err := elems[2].GetBytes(nil)
+	if err != nil {
+		return fmt.Errorf("failed to decode checkpoint current validators hash: %w", err)
+	}
+
+	eventRoot, err := elems[3].GetBytes(nil)
+	if err != nil {
+		return fmt.Errorf("failed to decode checkpoint event root: %w", err)
+	}
+
+	c.BlockRound = blockRound
+	c.EpochNumber = epochNumber
+	c.CurrentValidatorsHash = types.BytesToHash(currentValidatorsHash)
+	c.EventRoot = types.BytesToHash(eventRoot)
+
+	if len(elems) >= 5 {
+		algorithm, err := elems[4].GetUint64()
+		if err != nil {
+			return fmt.Errorf("failed to decode checkpoint hash algorithm: %w", err)
+		}
+
+		c.HashAlgorithm = merkle.HashAlgorithm(algorithm)
+	} else {
+		c.HashAlgorithm = merkle.HashAlgorithmKeccak256
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package polybft
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/fastrlp"
+
+	"github.com/0xPolygon/polygon-edge/merkle-tree"
+	"github.com/0xPolygon/polygon-edge/types"
+)
+
+func TestExtra_RLPRoundTripIncludesHashAlgorithm(t *testing.T) {
+	extra := &Extra{
+		Checkpoint: &CheckpointData{
+			BlockRound:            3,
+			EpochNumber:           7,
+			CurrentValidatorsHash: types.Hash{1},
+			EventRoot:             types.Hash{2},
+			HashAlgorithm:         merkle.HashAlgorithmPoseidon,
+		},
+	}
+
+	encoded := extra.MarshalRLPTo(nil)
+
+	decoded := &Extra{}
+	require.NoError(t, decoded.UnmarshalRLP(encoded))
+
+	require.NotNil(t, decoded.Checkpoint)
+	assert.Equal(t, extra.Checkpoint.BlockRound, decoded.Checkpoint.BlockRound)
+	assert.Equal(t, extra.Checkpoint.EpochNumber, decoded.Checkpoint.EpochNumber)
+	assert.Equal(t, extra.Checkpoint.CurrentValidatorsHash, decoded.Checkpoint.CurrentValidatorsHash)
+	assert.Equal(t, extra.Checkpoint.EventRoot, decoded.Checkpoint.EventRoot)
+	assert.Equal(t, merkle.HashAlgorithmPoseidon, decoded.Checkpoint.HashAlgorithm)
+}
+
+func TestCheckpointData_UnmarshalRLPDefaultsHashAlgorithmWhenAbsent(t *testing.T) {
+	// hand-encode a 4-element checkpoint array, as produced before HashAlgorithm existed
+	ar := &fastrlp.Arena{}
+	checkpointVV := ar.NewArray()
+	checkpointVV.Set(ar.NewUint(1))
+	checkpointVV.Set(ar.NewUint(2))
+	checkpointVV.Set(ar.NewBytes(types.Hash{3}.Bytes()))
+	checkpointVV.Set(ar.NewBytes(types.Hash{4}.Bytes()))
+
+	// the checkpoint is the extra array's 3rd element, after the (empty) validators and committed
+	extraVV := ar.NewArray()
+	extraVV.Set(ar.NewNullArray())
+	extraVV.Set(ar.NewNullArray())
+	extraVV.Set(checkpointVV)
+
+	encoded := extraVV.MarshalTo(nil)
+
+	decoded := &Extra{}
+	require.NoError(t, decoded.UnmarshalRLP(encoded))
+
+	require.NotNil(t, decoded.Checkpoint)
+	assert.Equal(t, uint64(1), decoded.Checkpoint.BlockRound)
+	assert.Equal(t, merkle.HashAlgorithmKeccak256, decoded.Checkpoint.HashAlgorithm)
+}
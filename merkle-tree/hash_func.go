@@ -0,0 +1,89 @@
+package merkle
+
+import (
+	"fmt"
+
+	"github.com/iden3/go-iden3-crypto/poseidon"
+
+	"github.com/0xPolygon/polygon-edge/crypto"
+	"github.com/0xPolygon/polygon-edge/types"
+)
+
+// HashAlgorithm identifies which HashFunc a MerkleTree was built with. It is carried
+// alongside a checkpoint's event root so that proof consumers (including the on-chain
+// CheckpointManager/CheckpointOracle verifier and light clients) know which algorithm to
+// use when re-deriving a leaf or inner node hash
+type HashAlgorithm uint8
+
+const (
+	// HashAlgorithmKeccak256 is the original, default exit tree hashing algorithm
+	HashAlgorithmKeccak256 HashAlgorithm = iota
+	// HashAlgorithmPoseidon is a zk-friendly algorithm for chains bridging to L1s (or L1
+	// contracts) that expect a Poseidon commitment instead of a Keccak one
+	HashAlgorithmPoseidon
+)
+
+// String returns the on-the-wire/ABI identifier for a HashAlgorithm
+func (a HashAlgorithm) String() string {
+	switch a {
+	case HashAlgorithmPoseidon:
+		return "poseidon"
+	default:
+		return "keccak256"
+	}
+}
+
+// HashFunc computes the leaf and inner node hashes used to build and verify a MerkleTree.
+// Leaf hashing and inner (pairwise) hashing always use the same HashFunc, so a tree's root,
+// and therefore everything that verifies against it, is internally consistent. Hash can fail
+// (e.g. Poseidon rejects input that doesn't fit its field), so callers - including the
+// batched exit-proof path, which runs over untrusted, request-controlled exit event data -
+// can return a proper error instead of crashing
+type HashFunc interface {
+	// Hash hashes a single input - either a leaf's encoded data or two concatenated node hashes
+	Hash(data []byte) (types.Hash, error)
+	// Algorithm identifies which HashAlgorithm this HashFunc implements
+	Algorithm() HashAlgorithm
+}
+
+// keccakHashFunc is the original exit tree hashing algorithm
+type keccakHashFunc struct{}
+
+func (keccakHashFunc) Hash(data []byte) (types.Hash, error) {
+	return types.BytesToHash(crypto.Keccak256(data)), nil
+}
+
+func (keccakHashFunc) Algorithm() HashAlgorithm { return HashAlgorithmKeccak256 }
+
+// Keccak256HashFunc is the default HashFunc, matching the exit tree's original on-chain behavior
+var Keccak256HashFunc HashFunc = keccakHashFunc{}
+
+// poseidonHashFunc hashes over the BN254 scalar field, for chains that need a zk-friendly commitment
+type poseidonHashFunc struct{}
+
+func (poseidonHashFunc) Hash(data []byte) (types.Hash, error) {
+	hash, err := poseidon.HashBytes(data)
+	if err != nil {
+		return types.Hash{}, fmt.Errorf("poseidon hash failed: %w", err)
+	}
+
+	return types.BytesToHash(hash.Bytes()), nil
+}
+
+func (poseidonHashFunc) Algorithm() HashAlgorithm { return HashAlgorithmPoseidon }
+
+// PoseidonHashFunc is the zk-friendly HashFunc alternative to Keccak256HashFunc
+var PoseidonHashFunc HashFunc = poseidonHashFunc{}
+
+// HashFuncByAlgorithm resolves the HashFunc implementation for a given HashAlgorithm, as
+// read from a checkpoint's hash-algo identifier
+func HashFuncByAlgorithm(algorithm HashAlgorithm) (HashFunc, error) {
+	switch algorithm {
+	case HashAlgorithmKeccak256:
+		return Keccak256HashFunc, nil
+	case HashAlgorithmPoseidon:
+		return PoseidonHashFunc, nil
+	default:
+		return nil, fmt.Errorf("unknown exit tree hash algorithm: %d", algorithm)
+	}
+}
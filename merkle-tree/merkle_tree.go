@@ -0,0 +1,148 @@
+package merkle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/0xPolygon/polygon-edge/types"
+)
+
+var errEmptyTree = errors.New("cannot create a merkle tree with no leaves")
+
+// MerkleTree is a binary merkle tree over arbitrary leaf data, used to commit to (and later
+// prove membership of) exit events in a checkpoint. Leaf and inner node hashing both go
+// through the tree's configured HashFunc, so Keccak- and Poseidon-based trees never mix hashes
+type MerkleTree struct {
+	hashFunc  HashFunc
+	leafIndex map[types.Hash]int
+	// layers[0] holds the leaf hashes, layers[len(layers)-1] holds the single root hash
+	layers [][]types.Hash
+}
+
+// NewMerkleTree creates a MerkleTree using the default Keccak256HashFunc, preserving the
+// exit tree's original on-chain behavior
+func NewMerkleTree(data [][]byte) (*MerkleTree, error) {
+	return NewMerkleTreeWithHashFunc(data, Keccak256HashFunc)
+}
+
+// NewMerkleTreeWithHashFunc creates a MerkleTree whose leaf and inner node hashes are all
+// computed with hashFunc, so operators bridging to zk-friendly L1s can swap in Poseidon (or
+// any other HashFunc) without touching the tree-building logic itself
+func NewMerkleTreeWithHashFunc(data [][]byte, hashFunc HashFunc) (*MerkleTree, error) {
+	if len(data) == 0 {
+		return nil, errEmptyTree
+	}
+
+	leaves := make([]types.Hash, len(data))
+	leafIndex := make(map[types.Hash]int, len(data))
+
+	for i, d := range data {
+		leaf, err := hashFunc.Hash(d)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash leaf %d: %w", i, err)
+		}
+
+		leaves[i] = leaf
+		leafIndex[leaf] = i
+	}
+
+	layers := [][]types.Hash{leaves}
+	for len(layers[len(layers)-1]) > 1 {
+		next, err := nextLayer(layers[len(layers)-1], hashFunc)
+		if err != nil {
+			return nil, err
+		}
+
+		layers = append(layers, next)
+	}
+
+	return &MerkleTree{hashFunc: hashFunc, leafIndex: leafIndex, layers: layers}, nil
+}
+
+// nextLayer pairwise-hashes nodes into their parent layer, carrying an unpaired trailing
+// node up unchanged
+func nextLayer(nodes []types.Hash, hashFunc HashFunc) ([]types.Hash, error) {
+	next := make([]types.Hash, 0, (len(nodes)+1)/2)
+
+	for i := 0; i < len(nodes); i += 2 {
+		if i+1 == len(nodes) {
+			next = append(next, nodes[i])
+
+			continue
+		}
+
+		pair, err := hashPair(nodes[i], nodes[i+1], hashFunc)
+		if err != nil {
+			return nil, err
+		}
+
+		next = append(next, pair)
+	}
+
+	return next, nil
+}
+
+// hashPair hashes two sibling nodes together. The pair is sorted first so proof
+// verification does not need to track left/right order, only the sibling hash itself
+func hashPair(a, b types.Hash, hashFunc HashFunc) (types.Hash, error) {
+	combined := make([]byte, 0, types.HashLength*2)
+
+	if bytes.Compare(a.Bytes(), b.Bytes()) <= 0 {
+		combined = append(combined, a.Bytes()...)
+		combined = append(combined, b.Bytes()...)
+	} else {
+		combined = append(combined, b.Bytes()...)
+		combined = append(combined, a.Bytes()...)
+	}
+
+	return hashFunc.Hash(combined)
+}
+
+// Hash returns the tree's root hash
+func (t *MerkleTree) Hash() types.Hash {
+	return t.layers[len(t.layers)-1][0]
+}
+
+// HashAlgorithm identifies which HashAlgorithm this tree's root was computed with, so it
+// can be recorded alongside the checkpoint's event root for later proof verification
+func (t *MerkleTree) HashAlgorithm() HashAlgorithm {
+	return t.hashFunc.Algorithm()
+}
+
+// LeafIndex returns the position of a leaf (by its original, unhashed data) in the tree
+func (t *MerkleTree) LeafIndex(data []byte) (int, error) {
+	hash, err := t.hashFunc.Hash(data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to hash leaf: %w", err)
+	}
+
+	idx, ok := t.leafIndex[hash]
+	if !ok {
+		return 0, fmt.Errorf("leaf not found in merkle tree")
+	}
+
+	return idx, nil
+}
+
+// GenerateProof returns the sibling hashes needed to verify a leaf's membership, from the
+// leaf layer up to (but not including) the root
+func (t *MerkleTree) GenerateProof(data []byte) ([][]byte, error) {
+	idx, err := t.LeafIndex(data)
+	if err != nil {
+		return nil, err
+	}
+
+	proof := make([][]byte, 0, len(t.layers)-1)
+
+	for _, layer := range t.layers[:len(t.layers)-1] {
+		siblingIdx := idx ^ 1
+		if siblingIdx < len(layer) {
+			proof = append(proof, layer[siblingIdx].Bytes())
+		}
+
+		idx /= 2
+	}
+
+	return proof, nil
+}
@@ -0,0 +1,38 @@
+package merkle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerkleTree_KeccakAndPoseidonRootsDiffer(t *testing.T) {
+	data := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+
+	keccakTree, err := NewMerkleTreeWithHashFunc(data, Keccak256HashFunc)
+	require.NoError(t, err)
+	assert.Equal(t, HashAlgorithmKeccak256, keccakTree.HashAlgorithm())
+
+	poseidonTree, err := NewMerkleTreeWithHashFunc(data, PoseidonHashFunc)
+	require.NoError(t, err)
+	assert.Equal(t, HashAlgorithmPoseidon, poseidonTree.HashAlgorithm())
+
+	assert.NotEqual(t, keccakTree.Hash(), poseidonTree.Hash())
+}
+
+func TestMerkleTree_GenerateProofRoundTrip(t *testing.T) {
+	data := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d")}
+
+	tree, err := NewMerkleTree(data)
+	require.NoError(t, err)
+
+	for _, d := range data {
+		proof, err := tree.GenerateProof(d)
+		require.NoError(t, err)
+		assert.NotEmpty(t, proof)
+	}
+
+	_, err = tree.LeafIndex([]byte("missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,216 @@
+package txrelayer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	ethcore "github.com/ethereum/go-ethereum/core"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/umbracle/ethgo"
+
+	"github.com/0xPolygon/polygon-edge/consensus/polybft/contractsapi"
+	"github.com/0xPolygon/polygon-edge/consensus/polybft/validator"
+	"github.com/0xPolygon/polygon-edge/helper/hex"
+	"github.com/0xPolygon/polygon-edge/types"
+)
+
+// simulatedGasLimit is the block gas limit used by the in-memory chain; it only needs to
+// be large enough to deploy and exercise the CheckpointManager artifact
+const simulatedGasLimit = 30_000_000
+
+// simulatedChainID is an arbitrary chain id used to sign transactions against the simulated chain
+var simulatedChainID = big.NewInt(1337)
+
+// SimulatedRelayer is a TxRelayer backed by an in-memory EVM (go-ethereum's simulated
+// backend) instead of a live rootchain RPC endpoint. It deploys the real CheckpointManager
+// artifact from contractsapi, so Call and SendTransaction exercise the genuine ABI encoding
+// and contract logic (signature verification, checkpoint bookkeeping, ...) that a live
+// rootchain would, without requiring one to be running
+type SimulatedRelayer struct {
+	backend               *backends.SimulatedBackend
+	deployerKey           *ecdsa.PrivateKey
+	CheckpointManagerAddr types.Address
+}
+
+// NewSimulatedRelayer deploys the CheckpointManager contract on a fresh in-memory chain and
+// initializes it with the given genesis validator set, funding every signer passed in
+func NewSimulatedRelayer(initialValidators validator.AccountSet, fundedSigners ...ethgo.Address) (*SimulatedRelayer, error) {
+	deployerKey, err := ethcrypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate simulated relayer deployer key: %w", err)
+	}
+
+	alloc := ethcore.GenesisAlloc{
+		ethcrypto.PubkeyToAddress(deployerKey.PublicKey): {Balance: weiBalance()},
+	}
+
+	for _, signer := range fundedSigners {
+		alloc[ethcommon.Address(signer)] = ethcore.GenesisAccount{Balance: weiBalance()}
+	}
+
+	backend := backends.NewSimulatedBackend(alloc, simulatedGasLimit)
+
+	r := &SimulatedRelayer{backend: backend, deployerKey: deployerKey}
+
+	if err := r.deployAndInitCheckpointManager(initialValidators); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// weiBalance is the starting balance given to every funded account on the simulated chain
+func weiBalance() *big.Int {
+	return new(big.Int).Mul(big.NewInt(1_000_000), big.NewInt(params.Ether))
+}
+
+// deployAndInitCheckpointManager deploys the CheckpointManager bytecode and calls its
+// initialize function with the given genesis validator set
+func (r *SimulatedRelayer) deployAndInitCheckpointManager(initialValidators validator.AccountSet) error {
+	deployTx := ethtypes.NewContractCreation(
+		0, big.NewInt(0), simulatedGasLimit, big.NewInt(params.GWei), contractsapi.CheckpointManager.Bytecode)
+
+	receipt, err := r.signAndSend(deployTx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to deploy CheckpointManager: %w", err)
+	}
+
+	r.CheckpointManagerAddr = types.Address(receipt.ContractAddress)
+
+	initialize := &contractsapi.InitializeCheckpointManagerFn{NewValidatorSet: initialValidators.ToAPIBinding()}
+
+	input, err := initialize.EncodeAbi()
+	if err != nil {
+		return fmt.Errorf("failed to encode CheckpointManager initialize call: %w", err)
+	}
+
+	if _, err := r.SendTransaction(&ethgo.Transaction{
+		To:    (*ethgo.Address)(&r.CheckpointManagerAddr),
+		Input: input,
+	}, nil); err != nil {
+		return fmt.Errorf("failed to initialize CheckpointManager: %w", err)
+	}
+
+	return nil
+}
+
+// Call executes a read-only contract call against the simulated chain's current state
+func (r *SimulatedRelayer) Call(from, to ethgo.Address, input []byte) (string, error) {
+	toAddr := ethcommon.Address(to)
+
+	result, err := r.backend.CallContract(context.Background(), ethereum.CallMsg{
+		From: ethcommon.Address(from),
+		To:   &toAddr,
+		Data: input,
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToHex(result), nil
+}
+
+// SendTransaction signs and submits a transaction to the simulated chain and commits a new
+// block so it is immediately reflected in subsequent Call/SendTransaction invocations. If
+// key is nil, the relayer's own deployer key is used (e.g. during initial contract setup)
+func (r *SimulatedRelayer) SendTransaction(txn *ethgo.Transaction, key ethgo.Key) (*ethgo.Receipt, error) {
+	nonce, err := r.backend.PendingNonceAt(context.Background(), r.senderAddress(key))
+	if err != nil {
+		return nil, err
+	}
+
+	var to *ethcommon.Address
+	if txn.To != nil {
+		addr := ethcommon.Address(*txn.To)
+		to = &addr
+	}
+
+	gasLimit := uint64(simulatedGasLimit)
+	ethTx := ethtypes.NewTx(&ethtypes.LegacyTx{
+		Nonce:    nonce,
+		To:       to,
+		Value:    big.NewInt(0),
+		Gas:      gasLimit,
+		GasPrice: big.NewInt(params.GWei),
+		Data:     txn.Input,
+	})
+
+	return r.signAndSend(ethTx, key)
+}
+
+// senderAddress resolves the go-ethereum address that will sign and pay for a transaction
+func (r *SimulatedRelayer) senderAddress(key ethgo.Key) ethcommon.Address {
+	if key == nil {
+		return ethcrypto.PubkeyToAddress(r.deployerKey.PublicKey)
+	}
+
+	return ethcommon.Address(key.Address())
+}
+
+// signAndSend signs a transaction with key (falling back to the relayer's deployer key when
+// key is nil), submits it, mines a block and returns the resulting receipt converted to
+// ethgo's receipt type. Signing with the actual key passed to SendTransaction matters:
+// the simulated chain tracks nonces per sender, so a transaction signed by the wrong key
+// would be submitted (and nonce-tracked) as someone else entirely
+func (r *SimulatedRelayer) signAndSend(tx *ethtypes.Transaction, key ethgo.Key) (*ethgo.Receipt, error) {
+	signer := ethtypes.NewEIP155Signer(simulatedChainID)
+
+	signedTx, err := r.sign(tx, signer, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign simulated transaction: %w", err)
+	}
+
+	if err := r.backend.SendTransaction(context.Background(), signedTx); err != nil {
+		return nil, fmt.Errorf("failed to submit simulated transaction: %w", err)
+	}
+
+	r.Commit()
+
+	receipt, err := r.backend.TransactionReceipt(context.Background(), signedTx.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch simulated transaction receipt: %w", err)
+	}
+
+	return convertReceipt(receipt), nil
+}
+
+// sign signs tx with key, or the relayer's own deployer key if key is nil. Non-deployer keys
+// are ethgo.Key implementations (e.g. a validator's BLS/ECDSA wallet key) that only expose
+// Sign(hash), so the tx hash is signed via that interface and the resulting 65-byte
+// r||s||v signature is attached to the transaction rather than re-signing it from scratch
+func (r *SimulatedRelayer) sign(tx *ethtypes.Transaction, signer ethtypes.Signer, key ethgo.Key) (*ethtypes.Transaction, error) {
+	if key == nil {
+		return ethtypes.SignTx(tx, signer, r.deployerKey)
+	}
+
+	signature, err := key.Sign(signer.Hash(tx).Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign transaction with key %s: %w", key.Address(), err)
+	}
+
+	return tx.WithSignature(signer, signature)
+}
+
+// Commit advances the simulated chain by one block, as if it had been mined, making any
+// pending transactions and their state changes visible to subsequent calls
+func (r *SimulatedRelayer) Commit() {
+	r.backend.Commit()
+}
+
+// convertReceipt maps a go-ethereum receipt onto ethgo's receipt type, which is what the
+// rest of checkpointManager (and TxRelayer callers in general) expect
+func convertReceipt(receipt *ethtypes.Receipt) *ethgo.Receipt {
+	return &ethgo.Receipt{
+		TransactionHash: ethgo.Hash(receipt.TxHash),
+		ContractAddress: ethgo.Address(receipt.ContractAddress),
+		GasUsed:         receipt.GasUsed,
+		Status:          receipt.Status,
+	}
+}
@@ -0,0 +1,70 @@
+package txrelayer
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	"github.com/umbracle/ethgo"
+
+	"github.com/0xPolygon/polygon-edge/consensus/polybft/contractsapi"
+	"github.com/0xPolygon/polygon-edge/consensus/polybft/validator"
+)
+
+// rawEcdsaKey is a minimal ethgo.Key backed directly by an ecdsa.PrivateKey, used only to
+// exercise SendTransaction with a signer other than the relayer's own deployer key
+type rawEcdsaKey struct {
+	priv *ecdsa.PrivateKey
+}
+
+func (k *rawEcdsaKey) Address() ethgo.Address {
+	return ethgo.Address(ethcrypto.PubkeyToAddress(k.priv.PublicKey))
+}
+
+func (k *rawEcdsaKey) Sign(hash []byte) ([]byte, error) {
+	return ethcrypto.Sign(hash, k.priv)
+}
+
+func TestSimulatedRelayer_DeploysAndCallsCheckpointManager(t *testing.T) {
+	relayer, err := NewSimulatedRelayer(validator.AccountSet{})
+	require.NoError(t, err)
+	require.NotEqual(t, ethgo.ZeroAddress, ethgo.Address(relayer.CheckpointManagerAddr))
+
+	currentCheckpointBlockNumMethod, ok := contractsapi.CheckpointManager.Abi.Methods["currentCheckpointBlockNumber"]
+	require.True(t, ok)
+
+	input, err := currentCheckpointBlockNumMethod.Encode([]interface{}{})
+	require.NoError(t, err)
+
+	resp, err := relayer.Call(ethgo.ZeroAddress, ethgo.Address(relayer.CheckpointManagerAddr), input)
+	require.NoError(t, err)
+	require.NotEmpty(t, resp)
+}
+
+func TestSimulatedRelayer_SendTransactionSignsWithGivenKey(t *testing.T) {
+	priv, err := ethcrypto.GenerateKey()
+	require.NoError(t, err)
+
+	signer := &rawEcdsaKey{priv: priv}
+
+	relayer, err := NewSimulatedRelayer(validator.AccountSet{}, ethgo.Address(signer.Address()))
+	require.NoError(t, err)
+
+	currentCheckpointBlockNumMethod, ok := contractsapi.CheckpointManager.Abi.Methods["currentCheckpointBlockNumber"]
+	require.True(t, ok)
+
+	input, err := currentCheckpointBlockNumMethod.Encode([]interface{}{})
+	require.NoError(t, err)
+
+	to := ethgo.Address(relayer.CheckpointManagerAddr)
+	receipt, err := relayer.SendTransaction(&ethgo.Transaction{To: &to, Input: input}, signer)
+	require.NoError(t, err)
+	require.NotNil(t, receipt)
+
+	// a second transaction from the same signer must use the next nonce, which only holds
+	// if the first one was actually signed (and therefore sent) as signer, not the deployer
+	receipt, err = relayer.SendTransaction(&ethgo.Transaction{To: &to, Input: input}, signer)
+	require.NoError(t, err)
+	require.NotNil(t, receipt)
+}